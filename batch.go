@@ -0,0 +1,119 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BatchResult is the outcome of one request sent as part of a batch.
+type BatchResult struct {
+	// Index is the position of the builder in the input.
+	Index    int
+	Builder  *RequestBuilder
+	Response *http.Response
+	Err      error
+	// Attempts is the number of attempts made, including retries, and
+	// Duration the time they took, as reported to OnComplete.
+	Attempts int
+	Duration time.Duration
+	// Cancelled reports whether the request failed, or was never sent,
+	// because the batch context was cancelled or its deadline expired,
+	// as opposed to failing on its own.
+	Cancelled bool
+}
+
+// Stream sends every builder received from builders, with at most
+// concurrency requests in flight, and emits each result as soon as it
+// completes. Requests are sent with ctx; once ctx is done no further
+// builders are consumed.
+//
+// The returned channel is closed after builders is closed (or ctx is done)
+// and all in-flight requests have finished, so callers must keep reading
+// until then. Callers are responsible for closing each response body.
+func Stream(ctx context.Context, builders <-chan *RequestBuilder, concurrency int) <-chan BatchResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make(chan BatchResult)
+	go func() {
+		defer close(results)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		sem := make(chan struct{}, concurrency)
+		for index := 0; ; index++ {
+			var builder *RequestBuilder
+			select {
+			case <-ctx.Done():
+				return
+			case b, ok := <-builders:
+				if !ok {
+					return
+				}
+				builder = b
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case sem <- struct{}{}:
+			}
+			wg.Add(1)
+			go func(index int, builder *RequestBuilder) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				resp, stats, err := builder.doStats(ctx, nil)
+				results <- BatchResult{
+					Index:     index,
+					Builder:   builder,
+					Response:  resp,
+					Err:       err,
+					Attempts:  stats.Attempts,
+					Duration:  stats.Duration,
+					Cancelled: cancelledBy(ctx, err),
+				}
+			}(index, builder)
+		}
+	}()
+	return results
+}
+
+// Batch sends builders with at most concurrency requests in flight and
+// returns their results in input order. All requests share ctx, so a
+// deadline set on it bounds the whole batch: when it expires, in-flight
+// requests are cancelled and builders not yet sent fail with ctx.Err().
+// Callers are responsible for closing each non-nil response body.
+func Batch(ctx context.Context, concurrency int, builders ...*RequestBuilder) []BatchResult {
+	queue := make(chan *RequestBuilder)
+	go func() {
+		defer close(queue)
+		for _, builder := range builders {
+			select {
+			case <-ctx.Done():
+				return
+			case queue <- builder:
+			}
+		}
+	}()
+	results := make([]BatchResult, len(builders))
+	sent := make([]bool, len(builders))
+	for result := range Stream(ctx, queue, concurrency) {
+		results[result.Index] = result
+		sent[result.Index] = true
+	}
+	for index, builder := range builders {
+		if !sent[index] {
+			results[index] = BatchResult{Index: index, Builder: builder, Err: ctx.Err(), Cancelled: true}
+		}
+	}
+	return results
+}
+
+// cancelledBy reports whether err was caused by ctx being done.
+func cancelledBy(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() == nil {
+		return false
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
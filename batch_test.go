@@ -0,0 +1,169 @@
+package httpx_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestStream(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	builders := make(chan *httpx.RequestBuilder)
+	go func() {
+		defer close(builders)
+		for i := 0; i < 10; i++ {
+			builders <- httpx.New(fmt.Sprintf("%s/%d", server.URL, i))
+		}
+	}()
+
+	seen := make(map[int]bool)
+	for result := range httpx.Stream(context.Background(), builders, 3) {
+		require.NoError(t, result.Err)
+		body, err := io.ReadAll(result.Response.Body)
+		result.Response.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("/%d", result.Index), string(body))
+		seen[result.Index] = true
+	}
+	assert.Len(t, seen, 10)
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(3))
+}
+
+func TestStream_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	builders := make(chan *httpx.RequestBuilder)
+	results := httpx.Stream(ctx, builders, 2)
+
+	builders <- httpx.New(server.URL)
+	result := <-results
+	require.NoError(t, result.Err)
+	result.Response.Body.Close()
+
+	// After cancellation the stream stops consuming and closes.
+	cancel()
+	for range results {
+	}
+	select {
+	case builders <- httpx.New(server.URL):
+		t.Fatal("builder consumed after cancellation")
+	default:
+	}
+}
+
+func TestBatch_Deadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte("fast"))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := httpx.Batch(ctx, 2,
+		httpx.New(server.URL+"/fast"),
+		httpx.New(server.URL+"/slow"),
+		httpx.New(server.URL+"/fast"),
+		httpx.New(server.URL+"/slow"),
+	)
+	assert.Less(t, time.Since(start), time.Second)
+
+	require.Len(t, results, 4)
+	for index, result := range results {
+		assert.Equal(t, index, result.Index)
+	}
+	require.NoError(t, results[0].Err)
+	results[0].Response.Body.Close()
+	assert.ErrorIs(t, results[1].Err, context.DeadlineExceeded)
+	require.NoError(t, results[2].Err)
+	results[2].Response.Body.Close()
+	assert.ErrorIs(t, results[3].Err, context.DeadlineExceeded)
+}
+
+func TestBatch_Cancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	results := httpx.Batch(ctx, 2,
+		httpx.New(closed.URL),
+		httpx.New(server.URL),
+		httpx.New(server.URL),
+		httpx.New(server.URL),
+	)
+
+	require.Error(t, results[0].Err)
+	assert.False(t, results[0].Cancelled, "connection errors are failures")
+	for _, result := range results[1:] {
+		assert.ErrorIs(t, result.Err, context.DeadlineExceeded)
+		assert.True(t, result.Cancelled)
+	}
+}
+
+func TestBatch_Attempts(t *testing.T) {
+	var flaky int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && atomic.AddInt32(&flaky, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	results := httpx.Batch(context.Background(), 2,
+		httpx.New(server.URL+"/stable").Retry(3).RetryOnStatus(),
+		httpx.New(server.URL+"/flaky").Retry(3).RetryOnStatus(),
+	)
+	for _, result := range results {
+		require.NoError(t, result.Err)
+		result.Response.Body.Close()
+		assert.Equal(t, http.StatusOK, result.Response.StatusCode)
+		assert.Positive(t, result.Duration)
+	}
+	assert.Equal(t, 1, results[0].Attempts)
+	assert.Equal(t, 3, results[1].Attempts)
+}
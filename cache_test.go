@@ -0,0 +1,41 @@
+package httpx_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestRequestBuilder_GetCached(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("cached body"))
+	}))
+	defer server.Close()
+
+	store := httpx.NewMemoryStore()
+	for _, want := range []string{"MISS", "HIT"} {
+		resp, err := httpx.New(server.URL).GetCached(store)
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.Header.Get(httpx.CacheStatusHeader))
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "cached body", string(body))
+	}
+	assert.Equal(t, []string{"", `"v1"`}, requests)
+}
@@ -0,0 +1,173 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Mode controls how a cassette handles requests.
+type Mode int
+
+const (
+	// ModeRecord sends requests over the network and saves every
+	// request/response pair to the cassette file.
+	ModeRecord Mode = iota
+	// ModeReplay serves responses from the cassette file without
+	// touching the network.
+	ModeReplay
+)
+
+// WithCassette records or replays the request using the cassette file at path.
+// Recording makes integration tests deterministic: run once in ModeRecord
+// against the real server, then in ModeReplay everywhere else.
+func (r *RequestBuilder) WithCassette(path string, mode Mode) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	next := r.httpClient().Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return r.RoundTripper(&cassette{path: path, mode: mode, next: next})
+}
+
+// cassetteMu serializes access to cassette files.
+var cassetteMu sync.Mutex
+
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+type recordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   []byte `json:"body,omitempty"`
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body,omitempty"`
+}
+
+// cassette is a http.RoundTripper that records to or replays from a file.
+type cassette struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded := recordedRequest{Method: req.Method, URL: req.URL.String()}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		recorded.Body = body
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	switch c.mode {
+	case ModeRecord:
+		return c.record(req, recorded)
+	case ModeReplay:
+		return c.replay(req, recorded)
+	default:
+		return nil, fmt.Errorf("httpx: unknown cassette mode %d", c.mode)
+	}
+}
+
+func (c *cassette) record(req *http.Request, recorded recordedRequest) (*http.Response, error) {
+	resp, err := c.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	cassetteMu.Lock()
+	defer cassetteMu.Unlock()
+	interactions, err := c.load()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	entry := interaction{
+		Request:  recorded,
+		Response: recordedResponse{StatusCode: resp.StatusCode, Header: resp.Header, Body: body},
+	}
+	if i := findInteraction(interactions, recorded); i >= 0 {
+		interactions[i] = entry
+	} else {
+		interactions = append(interactions, entry)
+	}
+	data, err := json.MarshalIndent(interactions, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(c.path, data, 0o644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *cassette) replay(req *http.Request, recorded recordedRequest) (*http.Response, error) {
+	cassetteMu.Lock()
+	interactions, err := c.load()
+	cassetteMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	i := findInteraction(interactions, recorded)
+	if i < 0 {
+		return nil, fmt.Errorf("httpx: no interaction for %s %s in cassette %s", recorded.Method, recorded.URL, c.path)
+	}
+	recordedResp := interactions[i].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recordedResp.StatusCode, http.StatusText(recordedResp.StatusCode)),
+		StatusCode:    recordedResp.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recordedResp.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(recordedResp.Body)),
+		ContentLength: int64(len(recordedResp.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (c *cassette) load() ([]interaction, error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+	var interactions []interaction
+	if err = json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("httpx: invalid cassette %s: %w", c.path, err)
+	}
+	return interactions, nil
+}
+
+// findInteraction returns the index of the interaction matching req, or -1.
+func findInteraction(interactions []interaction, req recordedRequest) int {
+	for i, entry := range interactions {
+		if entry.Request.Method == req.Method && entry.Request.URL == req.URL && bytes.Equal(entry.Request.Body, req.Body) {
+			return i
+		}
+	}
+	return -1
+}
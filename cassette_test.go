@@ -0,0 +1,51 @@
+package httpx_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestRequestBuilder_WithCassette(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		w.Header().Set("X-Echo", "true")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(append([]byte("echo: "), body...))
+	}))
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	url := server.URL + "/items?page=1"
+
+	// First run: record against the real server.
+	resp, err := httpx.New(url).Post().Json(map[string]string{"name": "a"}).WithCassette(path, httpx.ModeRecord).Do()
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `echo: {"name":"a"}`, string(body))
+	assert.Equal(t, 1, hits)
+
+	// Second run: replay without the network.
+	server.Close()
+	resp, err = httpx.New(url).Post().Json(map[string]string{"name": "a"}).WithCassette(path, httpx.ModeReplay).Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("X-Echo"))
+	body, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `echo: {"name":"a"}`, string(body))
+	assert.Equal(t, 1, hits)
+
+	// A request that was never recorded fails.
+	_, err = httpx.New(url).Post().Json(map[string]string{"name": "b"}).WithCassette(path, httpx.ModeReplay).Do()
+	assert.ErrorContains(t, err, "no interaction")
+}
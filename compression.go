@@ -0,0 +1,114 @@
+package httpx
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// CompressionPolicy decides whether a request asks the server for a
+// compressed response. Decompressing costs CPU, which is wasted on tiny
+// responses and on media types that are already compressed.
+type CompressionPolicy struct {
+	// MinSize is the smallest expected response size, in bytes, worth
+	// compressing. It is compared with the hint set by SizeHint; requests
+	// without a hint are always compressed.
+	MinSize int64
+	// ContentTypes, if not empty, limits compression to requests whose
+	// Accept header names one of these media types. A type ending in "/*",
+	// such as "text/*", matches every subtype. Requests without an Accept
+	// header, or accepting */*, are always compressed.
+	ContentTypes []string
+}
+
+// DefaultCompressionPolicy skips compression for responses expected to be
+// smaller than 1 KiB and otherwise only compresses text-based media types.
+var DefaultCompressionPolicy = CompressionPolicy{
+	MinSize:      1 << 10,
+	ContentTypes: []string{"text/*", "application/json", "application/xml", "application/javascript"},
+}
+
+// Compression applies policy to the request. When policy rules compression
+// out, the request sends "Accept-Encoding: identity" instead of letting the
+// transport ask for gzip. An Accept-Encoding header set explicitly is kept.
+func (r *RequestBuilder) Compression(policy CompressionPolicy) *RequestBuilder {
+	return r.Validate(func(req *http.Request) error {
+		if req.Header.Get("Accept-Encoding") == "" && !policy.allows(req.Header.Get("Accept"), r.sizeHint) {
+			req.Header.Set("Accept-Encoding", "identity")
+		}
+		return nil
+	})
+}
+
+// SizeHint tells the compression policy how large the response is expected
+// to be, in bytes.
+func (r *RequestBuilder) SizeHint(n int64) *RequestBuilder {
+	r.sizeHint = n
+	return r
+}
+
+func (p CompressionPolicy) allows(accept string, sizeHint int64) bool {
+	if sizeHint > 0 && sizeHint < p.MinSize {
+		return false
+	}
+	if len(p.ContentTypes) == 0 || accept == "" {
+		return true
+	}
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(value)
+		if err != nil {
+			continue
+		}
+		if mediaType == "*/*" {
+			return true
+		}
+		for _, pattern := range p.ContentTypes {
+			if matchMediaType(pattern, mediaType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchMediaType(pattern, mediaType string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mediaType, prefix+"/")
+	}
+	return strings.EqualFold(pattern, mediaType)
+}
+
+// BodyGzipFile sets the body to the contents of the file at path,
+// gzip-compressed while it is streamed, and sets Content-Encoding: gzip.
+// The file is reopened for retries and redirects, so it is never held in
+// memory and needs no cleanup by the caller.
+func (r *RequestBuilder) BodyGzipFile(path string) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	if _, err := os.Stat(path); err != nil {
+		r.err = err
+		return r
+	}
+	write := func(w io.Writer) error {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		gz := gzip.NewWriter(w)
+		if _, err = io.Copy(gz, file); err != nil {
+			return err
+		}
+		return gz.Close()
+	}
+	r.SetHeader("Content-Encoding", "gzip")
+	r.setBody(&pipeBody{write: write})
+	r.req.GetBody = func() (io.ReadCloser, error) {
+		return &pipeBody{write: write}, nil
+	}
+	return r
+}
@@ -0,0 +1,84 @@
+package httpx_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestRequestBuilder_Compression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Accept-Encoding")))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		builder *httpx.RequestBuilder
+		want    string
+	}{
+		{"no policy", httpx.New(server.URL), "gzip"},
+		{"no hints", httpx.New(server.URL).Compression(httpx.DefaultCompressionPolicy), "gzip"},
+		{"small response", httpx.New(server.URL).Compression(httpx.DefaultCompressionPolicy).SizeHint(100), "identity"},
+		{"large response", httpx.New(server.URL).Compression(httpx.DefaultCompressionPolicy).SizeHint(1 << 20), "gzip"},
+		{"json", httpx.New(server.URL).Compression(httpx.DefaultCompressionPolicy).SetHeader("Accept", "application/json"), "gzip"},
+		{"text subtype", httpx.New(server.URL).Compression(httpx.DefaultCompressionPolicy).SetHeader("Accept", "text/csv; q=0.9"), "gzip"},
+		{"image", httpx.New(server.URL).Compression(httpx.DefaultCompressionPolicy).SetHeader("Accept", "image/png"), "identity"},
+		{"explicit header", httpx.New(server.URL).Compression(httpx.DefaultCompressionPolicy).SizeHint(1).SetHeader("Accept-Encoding", "br"), "br"},
+		{"min size only", httpx.New(server.URL).Compression(httpx.CompressionPolicy{MinSize: 10}).SetHeader("Accept", "image/png").SizeHint(100), "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.builder.Do()
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(body))
+		})
+	}
+}
+
+func TestRequestBuilder_BodyGzipFile(t *testing.T) {
+	content := strings.Repeat("level=info msg=\"request handled\"\n", 1000)
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		body, err := io.ReadAll(gz)
+		assert.NoError(t, err)
+		assert.Equal(t, content, string(body))
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).Post().BodyGzipFile(path).Retry(2).RetryOnStatus().Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, attempts)
+
+	_, err = httpx.New(server.URL).Post().BodyGzipFile(filepath.Join(t.TempDir(), "missing")).Build()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,137 @@
+package httpx_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestRequestBuilder_DownloadParallel(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	var ranged int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			atomic.AddInt32(&ranged, 1)
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	require.NoError(t, httpx.New(server.URL).DownloadParallel(path, 4))
+
+	downloaded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&ranged))
+}
+
+func TestRequestBuilder_DownloadParallel_Fallback(t *testing.T) {
+	content := bytes.Repeat([]byte("fallback"), 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Range"))
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	require.NoError(t, httpx.New(server.URL).DownloadParallel(path, 4))
+
+	downloaded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+}
+
+func TestRequestBuilder_DownloadParallel_Decompress(t *testing.T) {
+	content := bytes.Repeat([]byte("compressed "), 1024)
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	_, err := gz.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	var ranged int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			atomic.AddInt32(&ranged, 1)
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(compressed.Bytes()))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	require.NoError(t, httpx.New(server.URL).DecompressOnDownload().DownloadParallel(path, 4))
+
+	downloaded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+	assert.Zero(t, atomic.LoadInt32(&ranged))
+}
+
+func TestRequestBuilder_DownloadParallel_Timeout(t *testing.T) {
+	content := bytes.Repeat([]byte("slow"), 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			select {
+			case <-time.After(600 * time.Millisecond):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "data.bin")
+	start := time.Now()
+	err := httpx.New(server.URL).Timeout(50*time.Millisecond).DownloadParallel(path, 4)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestRequestBuilder_DownloadParallel_PartFails(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Range") == "":
+		case strings.HasPrefix(r.Header.Get("Range"), "bytes=0-"):
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		default:
+			// The other parts stall until they are cancelled.
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(2 * time.Second):
+			}
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.bin")
+	start := time.Now()
+	err := httpx.New(server.URL).DownloadParallel(path, 4)
+	assert.ErrorContains(t, err, "500")
+	assert.Less(t, time.Since(start), time.Second)
+
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,51 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Group sends requests concurrently and cancels the remaining ones as soon
+// as one fails. It is a thin wrapper around errgroup.Group.
+type Group struct {
+	group *errgroup.Group
+	ctx   context.Context
+}
+
+// NewGroup returns a Group and a context derived from ctx that is
+// cancelled when a request in the group fails or Wait returns.
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	group, ctx := errgroup.WithContext(ctx)
+	return &Group{group: group, ctx: ctx}, ctx
+}
+
+// Go sends the request built by b in a new goroutine and passes the
+// response to handle, if not nil. The body is closed once handle returns.
+// A send error or an error returned by handle cancels the group.
+func (g *Group) Go(b *RequestBuilder, handle func(*http.Response) error) {
+	g.group.Go(func() error {
+		resp, err := b.DoWithContext(g.ctx)
+		if err != nil {
+			return err
+		}
+		defer closeBody(resp.Body)
+		if handle == nil {
+			return nil
+		}
+		return handle(resp)
+	})
+}
+
+// SetLimit limits the number of requests in flight to n.
+// A negative n removes the limit.
+func (g *Group) SetLimit(n int) {
+	g.group.SetLimit(n)
+}
+
+// Wait blocks until every request has finished and returns the first
+// error, if any.
+func (g *Group) Wait() error {
+	return g.group.Wait()
+}
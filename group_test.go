@@ -0,0 +1,57 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestGroup(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	errFailed := errors.New("upstream failed")
+	group, ctx := httpx.NewGroup(context.Background())
+
+	var mu sync.Mutex
+	var handled []int
+	for i := 0; i < 3; i++ {
+		group.Go(httpx.New(server.URL+"/slow"), func(resp *http.Response) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled = append(handled, resp.StatusCode)
+			return nil
+		})
+	}
+	group.Go(httpx.New(server.URL+"/fail"), func(resp *http.Response) error {
+		if resp.StatusCode != http.StatusOK {
+			return errFailed
+		}
+		return nil
+	})
+
+	start := time.Now()
+	err := group.Wait()
+	assert.ErrorIs(t, err, errFailed)
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Empty(t, handled)
+}
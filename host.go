@@ -0,0 +1,60 @@
+package httpx
+
+import (
+	"strings"
+	"sync"
+)
+
+// Option configures a RequestBuilder. Any builder method can be wrapped
+// in one, e.g.
+//
+//	func(r *httpx.RequestBuilder) { r.SetHeader("Authorization", "Bearer " + token) }
+type Option func(r *RequestBuilder)
+
+// Apply applies opts to the builder in order.
+func (r *RequestBuilder) Apply(opts ...Option) *RequestBuilder {
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+var (
+	hostOptionsMu sync.RWMutex
+	hostOptions   = map[string][]Option{}
+)
+
+// RegisterHost registers default options that New applies to every
+// builder whose URL targets host, such as the headers, authentication and
+// timeout of a known API. host is matched case-insensitively against the
+// URL's host with its port, e.g. "localhost:8080", and then without it,
+// e.g. "api.example.com". Relative URLs are matched after being resolved
+// against the base URL set by SetBaseURL.
+//
+// Registering a host again replaces its options, and registering it with
+// no options removes it. Options run before any method called on the
+// builder by the caller, which can therefore override them.
+func RegisterHost(host string, opts ...Option) {
+	hostOptionsMu.Lock()
+	defer hostOptionsMu.Unlock()
+	host = strings.ToLower(host)
+	if len(opts) == 0 {
+		delete(hostOptions, host)
+		return
+	}
+	hostOptions[host] = append([]Option(nil), opts...)
+}
+
+// applyHostOptions applies the options registered for the request host.
+func (r *RequestBuilder) applyHostOptions() {
+	if r.err != nil || r.req.URL.Host == "" {
+		return
+	}
+	hostOptionsMu.RLock()
+	opts, ok := hostOptions[strings.ToLower(r.req.URL.Host)]
+	if !ok {
+		opts = hostOptions[strings.ToLower(r.req.URL.Hostname())]
+	}
+	hostOptionsMu.RUnlock()
+	r.Apply(opts...)
+}
@@ -0,0 +1,74 @@
+package httpx_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestRegisterHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Header.Get("Authorization")+"|"+r.Header.Get("X-Client"))
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	httpx.RegisterHost(u.Host,
+		func(r *httpx.RequestBuilder) { r.SetHeader("Authorization", "Bearer secret") },
+		func(r *httpx.RequestBuilder) { r.SetHeader("X-Client", "httpx") },
+	)
+	defer httpx.RegisterHost(u.Host)
+
+	resp, err := httpx.New(server.URL + "/users").Do()
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret|httpx", string(body))
+
+	// Methods called by the caller override the defaults.
+	req, err := httpx.New(server.URL).SetHeader("X-Client", "custom").Build()
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+	assert.Equal(t, "custom", req.Header.Get("X-Client"))
+
+	// Other hosts are unaffected.
+	req, err = httpx.New("http://other.example.com").Build()
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	// Once unregistered, the defaults no longer apply.
+	httpx.RegisterHost(u.Host)
+	req, err = httpx.New(server.URL).Build()
+	require.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
+
+func TestRegisterHost_MatchesHostnameWithoutPort(t *testing.T) {
+	httpx.RegisterHost("API.example.com", func(r *httpx.RequestBuilder) { r.SetHeader("X-Client", "httpx") })
+	defer httpx.RegisterHost("api.example.com")
+
+	req, err := httpx.New("https://api.example.com:8443/v1").Build()
+	require.NoError(t, err)
+	assert.Equal(t, "httpx", req.Header.Get("X-Client"))
+
+	req, err = httpx.New("https://api.example.com:8443/v1").Reset().Build()
+	require.NoError(t, err)
+	assert.Equal(t, "httpx", req.Header.Get("X-Client"))
+}
+
+func TestRequestBuilder_Apply(t *testing.T) {
+	withJSON := func(r *httpx.RequestBuilder) { r.SetHeader("Accept", "application/json") }
+	req, err := httpx.New("http://example.com").Apply(withJSON, func(r *httpx.RequestBuilder) { r.Post() }).Build()
+	require.NoError(t, err)
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "application/json", req.Header.Get("Accept"))
+}
@@ -0,0 +1,41 @@
+package httpx
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WithSlog logs every attempt to send the request, including retries, to
+// logger. Each record carries the method, the URL with any password
+// redacted, the attempt number starting at 1, the duration and either the
+// response status or the error. Failed attempts are logged at
+// slog.LevelError, the others at slog.LevelInfo. A nil logger disables
+// logging.
+func (r *RequestBuilder) WithSlog(logger *slog.Logger) *RequestBuilder {
+	r.logger = logger
+	return r
+}
+
+// logAttempt logs the outcome of one attempt to the logger set by WithSlog.
+func (r *RequestBuilder) logAttempt(ctx context.Context, req *http.Request, attempt int, resp *http.Response, err error, duration time.Duration) {
+	if r.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.Redacted()),
+		slog.Int("attempt", attempt),
+		slog.Duration("duration", duration),
+	}
+	level := slog.LevelInfo
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	if err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	r.logger.LogAttrs(ctx, level, "httpx request", attrs...)
+}
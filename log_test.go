@@ -0,0 +1,91 @@
+package httpx_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+// captureHandler records the attributes of every log record it handles.
+type captureHandler struct {
+	mu      sync.Mutex
+	records []map[string]slog.Value
+	levels  []slog.Level
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(_ context.Context, record slog.Record) error {
+	attrs := make(map[string]slog.Value)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs[attr.Key] = attr.Value
+		return true
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, attrs)
+	h.levels = append(h.levels, record.Level)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *captureHandler) WithGroup(string) slog.Handler { return h }
+
+func TestRequestBuilder_WithSlog(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	handler := &captureHandler{}
+	resp, err := httpx.New(server.URL + "/users").Post().
+		Retry(2).RetryOnStatus().
+		WithSlog(slog.New(handler)).
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Len(t, handler.records, 2)
+	for i, status := range []int{http.StatusServiceUnavailable, http.StatusOK} {
+		record := handler.records[i]
+		assert.Equal(t, slog.LevelInfo, handler.levels[i])
+		assert.Equal(t, http.MethodPost, record["method"].String())
+		assert.Equal(t, server.URL+"/users", record["url"].String())
+		assert.Equal(t, int64(i+1), record["attempt"].Int64())
+		assert.Equal(t, int64(status), record["status"].Int64())
+		assert.Equal(t, slog.KindDuration, record["duration"].Kind())
+	}
+}
+
+func TestRequestBuilder_WithSlog_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	handler := &captureHandler{}
+	_, err := httpx.New("http://user:secret@" + server.Listener.Addr().String()).
+		WithSlog(slog.New(handler)).
+		Do()
+	require.Error(t, err)
+
+	require.Len(t, handler.records, 1)
+	record := handler.records[0]
+	assert.Equal(t, slog.LevelError, handler.levels[0])
+	assert.NotContains(t, record["url"].String(), "secret")
+	assert.Contains(t, record, "error")
+	assert.NotContains(t, record, "status")
+}
@@ -0,0 +1,223 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// MultipartBuilder builds a multipart/form-data request body.
+// Parts are written in the order they are added, as browsers do.
+type MultipartBuilder struct {
+	builder *RequestBuilder
+	parts   []multipartPart
+}
+
+type multipartPart struct {
+	field    string
+	value    string
+	filename string
+	// contentType defaults to application/octet-stream for file parts.
+	contentType string
+	// reader is nil for plain fields.
+	reader io.Reader
+}
+
+// Multipart starts building a multipart/form-data body.
+// Call Done to set it as the request body.
+func (r *RequestBuilder) Multipart() *MultipartBuilder {
+	return &MultipartBuilder{builder: r}
+}
+
+// Field adds a text field.
+func (m *MultipartBuilder) Field(name, value string) *MultipartBuilder {
+	m.parts = append(m.parts, multipartPart{field: name, value: value})
+	return m
+}
+
+// File adds a file part whose content is read from r.
+func (m *MultipartBuilder) File(field, filename string, r io.Reader) *MultipartBuilder {
+	m.parts = append(m.parts, multipartPart{field: field, filename: filename, reader: r})
+	return m
+}
+
+// FileWithType is like File but sends contentType, e.g. "image/jpeg", as
+// the Content-Type of the part, for servers that validate it.
+func (m *MultipartBuilder) FileWithType(field, filename, contentType string, r io.Reader) *MultipartBuilder {
+	m.parts = append(m.parts, multipartPart{field: field, filename: filename, contentType: contentType, reader: r})
+	return m
+}
+
+// Done sets the parts as the request body with the matching Content-Type
+// and returns the request builder. The body is streamed, so file contents
+// are never buffered in memory. If every file reader is an io.Seeker, the
+// body can be replayed for retries and redirects, and its Content-Length
+// is computed up front; otherwise the readers are consumed by the first
+// attempt and the body is sent with chunked encoding.
+func (m *MultipartBuilder) Done() *RequestBuilder {
+	r := m.builder
+	if r.err != nil {
+		return r
+	}
+	writer := multipart.NewWriter(io.Discard)
+	boundary := writer.Boundary()
+	write := func(w io.Writer) error {
+		return m.writeParts(w, boundary, true)
+	}
+	offsets, sizes, seekable := m.seekState()
+	body := &pipeBody{write: write}
+	r.SetHeader("Content-Type", writer.FormDataContentType())
+	r.setBody(body)
+	if !seekable {
+		return r
+	}
+	r.req.GetBody = func() (io.ReadCloser, error) {
+		// The previous body may still be copying from the readers; closing
+		// it waits for that to stop before they are rewound.
+		body.Close()
+		for i, part := range m.parts {
+			if seeker, ok := part.reader.(io.Seeker); ok {
+				if _, err := seeker.Seek(offsets[i], io.SeekStart); err != nil {
+					return nil, err
+				}
+			}
+		}
+		body = &pipeBody{write: write}
+		return body, nil
+	}
+	// The framing is measured by writing the parts without file contents.
+	var framing countingWriter
+	if err := m.writeParts(&framing, boundary, false); err != nil {
+		r.err = err
+		return r
+	}
+	length := int64(framing)
+	for _, size := range sizes {
+		length += size
+	}
+	r.req.ContentLength = length
+	return r
+}
+
+// writeParts writes the multipart body to w. File contents are skipped
+// unless contents is set.
+func (m *MultipartBuilder) writeParts(w io.Writer, boundary string, contents bool) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(boundary); err != nil {
+		return err
+	}
+	for _, part := range m.parts {
+		if !contents && part.reader != nil {
+			part.reader = strings.NewReader("")
+		}
+		if err := writePart(writer, part); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+// seekState returns the current position and remaining size of each file
+// reader, and whether all of them can seek.
+func (m *MultipartBuilder) seekState() (offsets, sizes []int64, ok bool) {
+	offsets = make([]int64, len(m.parts))
+	sizes = make([]int64, len(m.parts))
+	for i, part := range m.parts {
+		if part.reader == nil {
+			continue
+		}
+		seeker, ok := part.reader.(io.Seeker)
+		if !ok {
+			return nil, nil, false
+		}
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, nil, false
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, nil, false
+		}
+		if _, err = seeker.Seek(offset, io.SeekStart); err != nil {
+			return nil, nil, false
+		}
+		offsets[i], sizes[i] = offset, end-offset
+	}
+	return offsets, sizes, true
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
+
+// pipeBody streams the output of write through a pipe. The writing
+// goroutine starts on the first Read, so a body that is never sent does
+// not leak it, and Close waits for it to stop.
+type pipeBody struct {
+	write  func(w io.Writer) error
+	once   sync.Once
+	reader *io.PipeReader
+	done   chan struct{}
+}
+
+func (p *pipeBody) start() {
+	p.once.Do(func() {
+		pr, pw := io.Pipe()
+		p.reader = pr
+		p.done = make(chan struct{})
+		go func() {
+			defer close(p.done)
+			pw.CloseWithError(p.write(pw))
+		}()
+	})
+}
+
+func (p *pipeBody) Read(b []byte) (int, error) {
+	p.start()
+	if p.reader == nil {
+		// Closed before the first Read.
+		return 0, io.ErrClosedPipe
+	}
+	return p.reader.Read(b)
+}
+
+func (p *pipeBody) Close() error {
+	p.once.Do(func() {})
+	if p.reader == nil {
+		return nil
+	}
+	err := p.reader.Close()
+	<-p.done
+	return err
+}
+
+// quoteEscaper escapes quoted parameters of Content-Disposition the same
+// way mime/multipart does.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func writePart(writer *multipart.Writer, part multipartPart) error {
+	if part.reader == nil {
+		return writer.WriteField(part.field, part.value)
+	}
+	contentType := part.contentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(part.field), quoteEscaper.Replace(part.filename)))
+	header.Set("Content-Type", contentType)
+	w, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, part.reader)
+	return err
+}
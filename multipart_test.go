@@ -0,0 +1,263 @@
+package httpx_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+type receivedPart struct {
+	Field, Filename, Content string
+}
+
+func readParts(t *testing.T, r *http.Request) []receivedPart {
+	reader, err := r.MultipartReader()
+	require.NoError(t, err)
+	var parts []receivedPart
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(part)
+		require.NoError(t, err)
+		parts = append(parts, receivedPart{part.FormName(), part.FileName(), string(content)})
+	}
+}
+
+func TestRequestBuilder_Multipart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, []receivedPart{
+			{"title", "", "report"},
+			{"attachment", "a.txt", "first file"},
+			{"author", "", "alice"},
+			{"attachment", "b.txt", "second file"},
+		}, readParts(t, r))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).Post().
+		Multipart().
+		Field("title", "report").
+		File("attachment", "a.txt", strings.NewReader("first file")).
+		Field("author", "alice").
+		File("attachment", "b.txt", strings.NewReader("second file")).
+		Done().
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestMultipartBuilder_FileWithType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if !assert.NoError(t, err) {
+			return
+		}
+		var types []string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, `"photo".jpg`, part.FileName())
+			types = append(types, part.Header.Get("Content-Type"))
+		}
+		assert.Equal(t, []string{"image/jpeg", "application/octet-stream"}, types)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).Post().
+		Multipart().
+		FileWithType("photo", `"photo".jpg`, "image/jpeg", strings.NewReader("jpeg bytes")).
+		File("photo", `"photo".jpg`, strings.NewReader("raw bytes")).
+		Done().
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// patternReader yields n bytes without holding them in memory.
+type patternReader struct {
+	n int64
+}
+
+func (p *patternReader) Read(b []byte) (int, error) {
+	if p.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > p.n {
+		b = b[:p.n]
+	}
+	for i := range b {
+		b[i] = 'x'
+	}
+	p.n -= int64(len(b))
+	return len(b), nil
+}
+
+func TestMultipartBuilder_Streams(t *testing.T) {
+	const size = 64 << 20
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if !assert.NoError(t, err) {
+			return
+		}
+		part, err := reader.NextPart()
+		if !assert.NoError(t, err) {
+			return
+		}
+		n, err := io.Copy(io.Discard, part)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(size), n)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	resp, err := httpx.New(server.URL).Post().
+		Multipart().
+		File("upload", "big.bin", &patternReader{n: size}).
+		Done().
+		Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+	runtime.ReadMemStats(&after)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(size/4))
+}
+
+func TestMultipartBuilder_Replay(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		assert.Equal(t, []receivedPart{{"file", "a.txt", "content"}}, readParts(t, r))
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).Post().
+		Multipart().
+		File("file", "a.txt", strings.NewReader("content")).
+		Done().
+		Retry(2).
+		RetryOnStatus().
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, attempts)
+
+	// Readers that cannot seek are consumed by the first attempt.
+	_, err = httpx.New(server.URL).Post().
+		Multipart().
+		File("file", "a.txt", io.LimitReader(strings.NewReader("content"), 7)).
+		Done().
+		BodyBytes()
+	assert.ErrorIs(t, err, httpx.ErrBodyNotReplayable)
+}
+
+func TestMultipartBuilder_ReplayAfterEarlyResponse(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 8<<20)
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			// Answer before reading the body.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		reader, err := r.MultipartReader()
+		if !assert.NoError(t, err) {
+			return
+		}
+		part, err := reader.NextPart()
+		if !assert.NoError(t, err) {
+			return
+		}
+		n, err := io.Copy(io.Discard, part)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(content)), n)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).Post().
+		Multipart().
+		File("file", "big.bin", bytes.NewReader(content)).
+		Done().
+		Retry(3).
+		RetryOnStatus().
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+}
+
+func TestMultipartBuilder_ContentLength(t *testing.T) {
+	var contentLength int64
+	var transferEncoding []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength, transferEncoding = r.ContentLength, r.TransferEncoding
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		if r.ContentLength >= 0 {
+			assert.Equal(t, r.ContentLength, int64(len(body)))
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	file, err := os.CreateTemp(t.TempDir(), "upload")
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString("file contents")
+	require.NoError(t, err)
+	_, err = file.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+
+	resp, err := httpx.New(server.URL).Post().
+		Multipart().
+		Field("title", "report").
+		File("a", "a.txt", strings.NewReader("first")).
+		FileWithType("b", "b.txt", "text/plain", file).
+		Done().
+		Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Positive(t, contentLength)
+	assert.Empty(t, transferEncoding)
+
+	// A reader of unknown size falls back to chunked encoding.
+	resp, err = httpx.New(server.URL).Post().
+		Multipart().
+		File("a", "a.txt", io.LimitReader(strings.NewReader("first"), 5)).
+		Done().
+		Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, int64(-1), contentLength)
+	assert.Equal(t, []string{"chunked"}, transferEncoding)
+}
@@ -0,0 +1,61 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+)
+
+// UploadProgress registers fn to be called as the request body is sent,
+// with the number of bytes written so far and the total size. The total
+// is the size set with UploadSize, or else the known length of the body,
+// or -1 if neither is known. A retried body is reported from zero again.
+func (r *RequestBuilder) UploadProgress(fn func(written, total int64)) *RequestBuilder {
+	r.uploadProgress = fn
+	return r
+}
+
+// UploadSize sets the total reported by UploadProgress for a body whose
+// length the builder cannot infer, such as a file opened by the caller.
+// It does not set the Content-Length header.
+func (r *RequestBuilder) UploadSize(total int64) *RequestBuilder {
+	r.uploadSize = total
+	return r
+}
+
+// trackUpload wraps the body of req, and its replays, to report progress.
+func (r *RequestBuilder) trackUpload(req *http.Request) {
+	total := int64(-1)
+	switch {
+	case r.uploadSize > 0:
+		total = r.uploadSize
+	case req.ContentLength > 0:
+		total = req.ContentLength
+	}
+	req.Body = &progressBody{ReadCloser: req.Body, total: total, fn: r.uploadProgress}
+	if getBody := req.GetBody; getBody != nil {
+		req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return &progressBody{ReadCloser: body, total: total, fn: r.uploadProgress}, nil
+		}
+	}
+}
+
+// progressBody reports the bytes read from a request body.
+type progressBody struct {
+	io.ReadCloser
+	written int64
+	total   int64
+	fn      func(written, total int64)
+}
+
+func (p *progressBody) Read(b []byte) (int, error) {
+	n, err := p.ReadCloser.Read(b)
+	if n > 0 {
+		p.written += int64(n)
+		p.fn(p.written, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,58 @@
+package httpx_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestRequestBuilder_UploadProgress(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		_, err := io.Copy(io.Discard, req.Body)
+		require.NoError(t, err)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	type update struct{ written, total int64 }
+
+	tests := []struct {
+		name    string
+		builder *httpx.RequestBuilder
+		want    update
+	}{
+		{
+			name:    "known size",
+			builder: httpx.New("http://example.com").Post().JsonRaw([]byte(`"hello world"`)),
+			want:    update{13, 13},
+		},
+		{
+			name:    "unknown size",
+			builder: httpx.New("http://example.com").Post().Body(&chunkReader{chunks: []string{"hello ", "world"}}),
+			want:    update{11, -1},
+		},
+		{
+			name:    "supplied size",
+			builder: httpx.New("http://example.com").Post().Body(&chunkReader{chunks: []string{"hello ", "world"}}).UploadSize(11),
+			want:    update{11, 11},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updates []update
+			resp, err := tt.builder.
+				RoundTripper(transport).
+				UploadProgress(func(written, total int64) {
+					updates = append(updates, update{written, total})
+				}).
+				Do()
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.NotEmpty(t, updates)
+			assert.Equal(t, tt.want, updates[len(updates)-1])
+		})
+	}
+}
@@ -0,0 +1,267 @@
+package httpx
+
+import (
+	"fmt"
+	urlpkg "net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NestingStyle controls how QueryStructWith names the parameters of
+// nested structs and maps.
+type NestingStyle int
+
+const (
+	// NestDotted joins names with dots, e.g. filter.status=open.
+	NestDotted NestingStyle = iota
+	// NestBrackets wraps nested names in brackets, e.g. filter[status]=open.
+	NestBrackets
+)
+
+// QueryStyle controls how query parameters are rendered in the URL.
+type QueryStyle int
+
+const (
+	// QueryStandard encodes parameters like url.Values.Encode: spaces
+	// become '+' and a parameter with several values repeats its key,
+	// e.g. q=a+b&tag=x&tag=y.
+	QueryStandard QueryStyle = iota
+	// QueryPercentSpaces is like QueryStandard but encodes spaces as
+	// %20, e.g. q=a%20b.
+	QueryPercentSpaces
+	// QueryCommaJoined is like QueryStandard but joins the values of a
+	// parameter with commas, e.g. tag=x,y. Commas inside a value are
+	// still escaped, so the values can be told apart.
+	QueryCommaJoined
+)
+
+// QueryEncoding sets how query parameters added afterwards by Query,
+// AddQuery, AddQueryValues and QueryStruct are rendered. Parameters
+// already in the URL are left as they are.
+func (r *RequestBuilder) QueryEncoding(style QueryStyle) *RequestBuilder {
+	r.queryStyle = style
+	return r
+}
+
+// encode renders values in style s, sorted by key.
+func (s QueryStyle) encode(values urlpkg.Values) string {
+	if s == QueryStandard {
+		return values.Encode()
+	}
+	escape := urlpkg.QueryEscape
+	if s == QueryPercentSpaces {
+		// QueryEscape encodes a literal '+' as %2B, so every '+' left is a space.
+		escape = func(v string) string {
+			return strings.ReplaceAll(urlpkg.QueryEscape(v), "+", "%20")
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf strings.Builder
+	for _, key := range keys {
+		escaped := make([]string, len(values[key]))
+		for i, v := range values[key] {
+			escaped[i] = escape(v)
+		}
+		if s == QueryCommaJoined && len(escaped) > 0 {
+			escaped = []string{strings.Join(escaped, ",")}
+		}
+		for _, v := range escaped {
+			if buf.Len() > 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(escape(key))
+			buf.WriteByte('=')
+			buf.WriteString(v)
+		}
+	}
+	return buf.String()
+}
+
+// QueryStruct adds the exported fields of the struct v, or of the struct
+// v points to, as query parameters, naming nested fields with NestDotted.
+// See QueryStructWith.
+func (r *RequestBuilder) QueryStruct(v interface{}) *RequestBuilder {
+	return r.QueryStructWith(v, NestDotted)
+}
+
+// QueryStructWith adds the exported fields of the struct v, or of the
+// struct v points to, as query parameters.
+//
+// A field is named by its `url` tag, or by its Go name if untagged; the
+// tag "-" skips it and the "omitempty" option skips its zero value. Nil
+// pointers are always skipped. Slices and arrays repeat the parameter,
+// time.Time values are formatted as RFC 3339, and nested structs and maps
+// are flattened into names joined according to style. Fields of embedded
+// structs are promoted without a prefix.
+func (r *RequestBuilder) QueryStructWith(v interface{}, style NestingStyle) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	values, err := structValues(v, style)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.appendQuery(r.queryStyle.encode(values))
+	return r
+}
+
+// structValues encodes the struct v, or the struct v points to, as
+// described by QueryStructWith.
+func structValues(v interface{}, style NestingStyle) (urlpkg.Values, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("httpx: expected a struct, got %T", v)
+	}
+	values := urlpkg.Values{}
+	if err := (queryEncoder{values: values, style: style}).encodeStruct("", rv); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+type queryEncoder struct {
+	values urlpkg.Values
+	style  NestingStyle
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (e queryEncoder) key(prefix, name string) string {
+	switch {
+	case prefix == "":
+		return name
+	case e.style == NestBrackets:
+		return prefix + "[" + name + "]"
+	default:
+		return prefix + "." + name
+	}
+}
+
+func (e queryEncoder) encodeStruct(prefix string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, omitEmpty := parseQueryTag(field)
+		if name == "-" {
+			continue
+		}
+		fv := rv.Field(i)
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+		if field.Anonymous && !hasTagName(field) && indirectType(field.Type).Kind() == reflect.Struct {
+			if fv.Kind() == reflect.Ptr && fv.IsNil() {
+				continue
+			}
+			if err := e.encodeStruct(prefix, reflect.Indirect(fv)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.encode(e.key(prefix, name), fv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e queryEncoder) encode(key string, rv reflect.Value) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Type() == timeType {
+		e.values.Add(key, rv.Interface().(time.Time).Format(time.RFC3339))
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		return e.encodeStruct(key, rv)
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("httpx: unsupported query map key type %s", rv.Type().Key())
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			if err := e.encode(e.key(key, k.String()), rv.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := e.encode(key, rv.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	value, err := formatQueryValue(rv)
+	if err != nil {
+		return fmt.Errorf("httpx: query parameter %q: %w", key, err)
+	}
+	e.values.Add(key, value)
+	return nil
+}
+
+func formatQueryValue(rv reflect.Value) (string, error) {
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits()), nil
+	default:
+		return "", fmt.Errorf("unsupported type %s", rv.Type())
+	}
+}
+
+// parseQueryTag returns the parameter name of field and whether its
+// zero value is omitted.
+func parseQueryTag(field reflect.StructField) (name string, omitEmpty bool) {
+	tag := field.Tag.Get("url")
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty
+}
+
+func hasTagName(field reflect.StructField) bool {
+	name, _, _ := strings.Cut(field.Tag.Get("url"), ",")
+	return name != ""
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
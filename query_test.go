@@ -0,0 +1,114 @@
+package httpx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+type Page struct {
+	Limit  int    `url:"limit"`
+	Cursor string `url:"cursor,omitempty"`
+}
+
+type searchFilter struct {
+	Status string            `url:"status"`
+	Owner  *string           `url:"owner"`
+	Labels map[string]string `url:"labels"`
+}
+
+type searchQuery struct {
+	Page
+	Q       string        `url:"q"`
+	Tags    []string      `url:"tag"`
+	Since   time.Time     `url:"since,omitempty"`
+	Filter  searchFilter  `url:"filter"`
+	Extra   *searchFilter `url:"extra"`
+	Skipped string        `url:"-"`
+	Verbose bool
+	hidden  string
+}
+
+func TestRequestBuilder_QueryStruct(t *testing.T) {
+	query := &searchQuery{
+		Page:    Page{Limit: 10},
+		Q:       "go http",
+		Tags:    []string{"a", "b"},
+		Filter:  searchFilter{Status: "open", Labels: map[string]string{"team": "web", "area": "api"}},
+		Skipped: "x",
+		hidden:  "y",
+	}
+
+	req, err := httpx.New("http://example.com?x=1").QueryStruct(query).Build()
+	require.NoError(t, err)
+	assert.Equal(t,
+		"x=1&Verbose=false&filter.labels.area=api&filter.labels.team=web&filter.status=open&limit=10&q=go+http&tag=a&tag=b",
+		req.URL.RawQuery)
+
+	req, err = httpx.New("http://example.com").QueryStructWith(query, httpx.NestBrackets).Build()
+	require.NoError(t, err)
+	assert.Equal(t,
+		"Verbose=false&filter%5Blabels%5D%5Barea%5D=api&filter%5Blabels%5D%5Bteam%5D=web&filter%5Bstatus%5D=open&limit=10&q=go+http&tag=a&tag=b",
+		req.URL.RawQuery)
+	assert.Equal(t, "web", req.URL.Query().Get("filter[labels][team]"))
+}
+
+func TestRequestBuilder_QueryStruct_PointersAndOmitEmpty(t *testing.T) {
+	owner := "alice"
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	query := searchQuery{
+		Page:   Page{Cursor: "c1"},
+		Since:  since,
+		Filter: searchFilter{Owner: &owner},
+		Extra:  &searchFilter{Status: "closed"},
+	}
+
+	req, err := httpx.New("http://example.com").QueryStruct(query).Build()
+	require.NoError(t, err)
+	values := req.URL.Query()
+	assert.Equal(t, "c1", values.Get("cursor"))
+	assert.Equal(t, "0", values.Get("limit"))
+	assert.Equal(t, "2024-01-02T03:04:05Z", values.Get("since"))
+	assert.Equal(t, "alice", values.Get("filter.owner"))
+	assert.Equal(t, "closed", values.Get("extra.status"))
+	assert.NotContains(t, values, "extra.owner")
+}
+
+func TestRequestBuilder_QueryStruct_Invalid(t *testing.T) {
+	_, err := httpx.New("http://example.com").QueryStruct("not a struct").Build()
+	assert.Error(t, err)
+
+	_, err = httpx.New("http://example.com").QueryStruct(struct{ C chan int }{}).Build()
+	assert.Error(t, err)
+}
+
+func TestRequestBuilder_QueryEncoding(t *testing.T) {
+	tests := []struct {
+		name  string
+		style httpx.QueryStyle
+		want  string
+	}{
+		{"standard", httpx.QueryStandard, "q=go+http&tag=a&tag=b%2Cc&x=1+%2B+1"},
+		{"percent spaces", httpx.QueryPercentSpaces, "q=go%20http&tag=a&tag=b%2Cc&x=1%20%2B%201"},
+		{"comma joined", httpx.QueryCommaJoined, "q=go+http&tag=a,b%2Cc&x=1+%2B+1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := httpx.New("http://example.com").
+				QueryEncoding(tt.style).
+				AddQuery("q", "go http").
+				AddQueryValues("tag", "a", "b,c").
+				QueryStruct(struct {
+					X string `url:"x"`
+				}{X: "1 + 1"}).
+				Build()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, req.URL.RawQuery)
+			assert.Equal(t, "go http", req.URL.Query().Get("q"))
+		})
+	}
+}
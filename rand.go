@@ -0,0 +1,48 @@
+package httpx
+
+import (
+	cryptorand "crypto/rand"
+	"math/rand"
+	"sync"
+)
+
+var (
+	randMu  sync.Mutex
+	randGen *rand.Rand
+)
+
+// SetRandSource makes jitter and request ID generation draw from src, so
+// tests can reproduce them exactly. A nil src restores the defaults:
+// crypto/rand for request IDs and the math/rand global source for jitter.
+// The source must not be used elsewhere while it is installed.
+func SetRandSource(src rand.Source) {
+	randMu.Lock()
+	defer randMu.Unlock()
+	if src == nil {
+		randGen = nil
+		return
+	}
+	randGen = rand.New(src)
+}
+
+// randomBytes fills b with random bytes.
+func randomBytes(b []byte) error {
+	randMu.Lock()
+	defer randMu.Unlock()
+	if randGen == nil {
+		_, err := cryptorand.Read(b)
+		return err
+	}
+	_, err := randGen.Read(b)
+	return err
+}
+
+// randomFloat64 returns a random number in [0.0, 1.0).
+func randomFloat64() float64 {
+	randMu.Lock()
+	defer randMu.Unlock()
+	if randGen == nil {
+		return rand.Float64()
+	}
+	return randGen.Float64()
+}
@@ -0,0 +1,35 @@
+package httpx_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestSetRandSource(t *testing.T) {
+	defer httpx.SetRandSource(nil)
+	backoff := httpx.ExponentialBackoff{Base: 100 * time.Millisecond, Jitter: 0.5}
+
+	generate := func() (string, []time.Duration) {
+		httpx.SetRandSource(rand.NewSource(42))
+		id := httpx.New("http://example.com").RequestID().CurrentRequestID()
+		return id, delays(backoff, 3)
+	}
+	firstID, firstDelays := generate()
+	secondID, secondDelays := generate()
+
+	assert.Equal(t, firstID, secondID)
+	assert.Equal(t, firstDelays, secondDelays)
+	for i, delay := range firstDelays {
+		full := 100 * time.Millisecond << i
+		assert.LessOrEqual(t, delay, full)
+		assert.GreaterOrEqual(t, delay, full/2)
+	}
+
+	httpx.SetRandSource(nil)
+	assert.NotEqual(t, firstID, httpx.New("http://example.com").RequestID().CurrentRequestID())
+}
@@ -4,26 +4,182 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
 	"net/http"
 	urlpkg "net/url"
 	"strings"
+	"sync"
+	"time"
 )
 
 // New creates a new RequestBuilder with the provided URL.
 // It initializes the request with a GET method.
+// A relative URL is resolved against the base URL set with SetBaseURL,
+// and options registered with RegisterHost for its host are applied.
 func New(url string) *RequestBuilder {
+	if strings.TrimSpace(url) == "" {
+		return &RequestBuilder{err: ErrEmptyURL}
+	}
 	req, err := http.NewRequest(http.MethodGet, url, nil)
-	return &RequestBuilder{req: req, err: err}
+	r := &RequestBuilder{req: req, err: err, rawURL: url}
+	if base := getBaseURL(); base != nil && err == nil && !req.URL.IsAbs() {
+		r.resolveURL(base)
+	}
+	r.applyHostOptions()
+	return r
+}
+
+// ErrEmptyURL is returned when a builder is created with an empty URL.
+var ErrEmptyURL = errors.New("httpx: empty URL")
+
+var (
+	baseURLMu sync.RWMutex
+	baseURL   *urlpkg.URL
+)
+
+// SetBaseURL sets the base URL that New resolves relative URLs against,
+// e.g. "/users" becomes "https://api.example.com/users".
+// An empty base clears it.
+func SetBaseURL(base string) error {
+	var u *urlpkg.URL
+	if base != "" {
+		var err error
+		if u, err = urlpkg.Parse(base); err != nil {
+			return err
+		}
+	}
+	baseURLMu.Lock()
+	defer baseURLMu.Unlock()
+	baseURL = u
+	return nil
+}
+
+func getBaseURL() *urlpkg.URL {
+	baseURLMu.RLock()
+	defer baseURLMu.RUnlock()
+	return baseURL
+}
+
+// BaseURL resolves the request URL against base, so a builder created
+// with a relative URL targets base's host.
+func (r *RequestBuilder) BaseURL(base string) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	u, err := urlpkg.Parse(base)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.resolveURL(u)
+	return r
+}
+
+// Reset returns the builder to the state New left it in, with the URL it
+// was created with and nothing else: the method is GET and headers, body,
+// retries, client and every other setting are cleared. This lets one
+// builder send a series of requests in a loop. Like the rest of the
+// builder, Reset is not safe for concurrent use, and it must not be called
+// while a request sent by the builder is still in progress.
+func (r *RequestBuilder) Reset() *RequestBuilder {
+	*r = *New(r.rawURL)
+	return r
+}
+
+// String returns a short description of the request for logs and error
+// messages, such as "POST https://api.example.com/users (3 attempts)".
+// Passwords in the URL are redacted and the body is not read.
+func (r *RequestBuilder) String() string {
+	if r.req == nil {
+		return fmt.Sprintf("invalid request: %v", r.err)
+	}
+	s := r.req.Method + " " + r.req.URL.Redacted()
+	if r.retryTimes > 1 {
+		s += fmt.Sprintf(" (%d attempts)", r.retryTimes)
+	}
+	if r.err != nil {
+		s += fmt.Sprintf(" (error: %v)", r.err)
+	}
+	return s
+}
+
+// Normalize rewrites the request URL into a canonical form, which keeps
+// cache keys and signatures stable: the scheme and host are lower-cased,
+// the default port for the scheme is dropped and repeated slashes in the
+// path are collapsed.
+func (r *RequestBuilder) Normalize() *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	u := r.req.URL
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Path = collapseSlashes(u.Path)
+	u.RawPath = collapseSlashes(u.RawPath)
+	r.req.Host = u.Host
+	return r
+}
+
+func collapseSlashes(path string) string {
+	for strings.Contains(path, "//") {
+		path = strings.ReplaceAll(path, "//", "/")
+	}
+	return path
+}
+
+func (r *RequestBuilder) resolveURL(base *urlpkg.URL) {
+	r.req.URL = base.ResolveReference(r.req.URL)
+	r.req.Host = r.req.URL.Host
 }
 
 // RequestBuilder is a builder for http.Request.
 // It provides methods to set up the request.
 type RequestBuilder struct {
-	retryTimes uint
-	err        error
-	req        *http.Request
-	client     *http.Client
+	retryTimes         uint
+	err                error
+	req                *http.Request
+	client             *http.Client
+	errorBody          interface{}
+	backoff            Backoff
+	retryIf            RetryPolicy
+	retryBudget        *RetryBudget
+	onRetry            func(attempt int, resp *http.Response, err error)
+	noContentLength    bool
+	decompressDownload bool
+	metadata           map[string]string
+	onComplete         func(Stats)
+	retryOnBodyError   bool
+	transport          *http.Transport
+	strictJSON         bool
+	expectations       []func(*http.Response) error
+	sleep              func(ctx context.Context, d time.Duration) error
+	sizeHint           int64
+	uploadProgress     func(written, total int64)
+	uploadSize         int64
+	rawURL             string
+	statusErrorMapper  func(code int) error
+	useNumber          bool
+	timeout            time.Duration
+	deadline           time.Time
+	beforeSend         []func(*http.Request) error
+	queryStyle         QueryStyle
+	noBodyReplay       bool
+	logger             *slog.Logger
+	unixSocket         string
+	dialChained        bool
+	baseDial           dialFunc
+
+	// hooks run in order against the request when it is built.
+	hooks []func(*http.Request) error
 }
 
 // Err returns the error that occurred while building the request.
@@ -33,6 +189,9 @@ func (r *RequestBuilder) Err() error {
 
 // Method sets the HTTP method for the request.
 func (r *RequestBuilder) Method(method string) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
 	r.req.Method = method
 	return r
 }
@@ -72,13 +231,29 @@ func (r *RequestBuilder) Options() *RequestBuilder { return r.Method(http.Method
 // Trace sets the HTTP method to TRACE.
 func (r *RequestBuilder) Trace() *RequestBuilder { return r.Method(http.MethodTrace) }
 
-// Body sets the body for the request.
+// Body sets the body for the request. Unlike the bodies set by helpers
+// such as Json, PostForm, Encode and Multipart, an arbitrary body cannot be
+// read twice, so it is not resent on retries or on 307 and 308 redirects,
+// which preserve the method and body, unless GetBody is also set.
 func (r *RequestBuilder) Body(body io.ReadCloser) *RequestBuilder {
+	return r.setBody(body)
+}
+
+// setBody sets the body for the request. ContentLength and GetBody are
+// inferred when body is one of the in-memory reader types, so helpers
+// must pass those readers unwrapped.
+func (r *RequestBuilder) setBody(body io.Reader) *RequestBuilder {
 	if r.err != nil {
 		return r
 	}
-	r.req.Body = body
-	switch v := body.(io.Reader).(type) {
+	rc, ok := body.(io.ReadCloser)
+	if !ok && body != nil {
+		rc = io.NopCloser(body)
+	}
+	r.req.Body = rc
+	r.req.ContentLength = 0
+	r.req.GetBody = nil
+	switch v := body.(type) {
 	case *bytes.Buffer:
 		r.req.ContentLength = int64(v.Len())
 		buf := v.Bytes()
@@ -122,6 +297,104 @@ func (r *RequestBuilder) Body(body io.ReadCloser) *RequestBuilder {
 	return r
 }
 
+// GetBody sets the function that returns a fresh copy of the body, for
+// bodies whose type Body cannot rewind on its own. It lets retries and
+// redirects resend arbitrary bodies. Call it after setting the body.
+func (r *RequestBuilder) GetBody(fn func() (io.ReadCloser, error)) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	r.req.GetBody = fn
+	return r
+}
+
+// BodyFS sets the body to the contents of the file name in fsys, such as
+// a payload bundled into the binary with an embed.FS. Content-Length is
+// set from the file size. The file is opened when the body is first read
+// and reopened for retries and redirects, so a request that is never sent
+// holds no file open.
+func (r *RequestBuilder) BodyFS(fsys fs.FS, name string) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	if info.IsDir() {
+		r.err = fmt.Errorf("httpx: %s is a directory", name)
+		return r
+	}
+	if info.Size() == 0 {
+		// Only http.NoBody is sent with an explicit Content-Length of 0.
+		return r.setBody(strings.NewReader(""))
+	}
+	r.setBody(&fsBody{fsys: fsys, name: name})
+	r.req.ContentLength = info.Size()
+	r.req.GetBody = func() (io.ReadCloser, error) {
+		return &fsBody{fsys: fsys, name: name}, nil
+	}
+	return r
+}
+
+// fsBody reads a file from an fs.FS, opening it on the first Read.
+type fsBody struct {
+	fsys fs.FS
+	name string
+	file fs.File
+}
+
+func (b *fsBody) Read(p []byte) (int, error) {
+	if b.file == nil {
+		file, err := b.fsys.Open(b.name)
+		if err != nil {
+			return 0, err
+		}
+		b.file = file
+	}
+	return b.file.Read(p)
+}
+
+func (b *fsBody) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	return b.file.Close()
+}
+
+// ErrBodyNotReplayable is returned when a body must be read again
+// but has no GetBody function to produce a fresh copy.
+var ErrBodyNotReplayable = errors.New("httpx: request body cannot be replayed")
+
+// BodyBytes returns a copy of the request body without consuming it,
+// which is handy for asserting on request construction in tests.
+// It returns ErrBodyNotReplayable if the body cannot be re-read.
+func (r *RequestBuilder) BodyBytes() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.req.Body == nil || r.req.Body == http.NoBody {
+		return nil, nil
+	}
+	if r.req.GetBody == nil {
+		return nil, ErrBodyNotReplayable
+	}
+	body, err := r.req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// NoContentLength forces the body to be sent without a Content-Length
+// header, using chunked transfer encoding, even when its length is known.
+func (r *RequestBuilder) NoContentLength() *RequestBuilder {
+	r.noContentLength = true
+	return r
+}
+
 // SetHeader sets a header for the request.
 func (r *RequestBuilder) SetHeader(key, value string) *RequestBuilder {
 	if r.err != nil {
@@ -131,6 +404,148 @@ func (r *RequestBuilder) SetHeader(key, value string) *RequestBuilder {
 	return r
 }
 
+// SetRawHeader sets a header without canonicalizing its key, for servers
+// that expect a specific casing such as "x-api-key". HTTP/1 requests carry
+// the key exactly as given; HTTP/2 lower-cases all field names regardless,
+// and servers built on net/http canonicalize keys when reading them.
+func (r *RequestBuilder) SetRawHeader(key, value string) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	r.req.Header[key] = []string{value}
+	return r
+}
+
+// WithHeader merges h into the request headers, keeping every value
+// of multi-valued keys. Existing headers are preserved.
+func (r *RequestBuilder) WithHeader(h http.Header) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	for key, values := range h {
+		for _, value := range values {
+			r.req.Header.Add(key, value)
+		}
+	}
+	return r
+}
+
+// ReplaceHeaders discards every header set so far, including ones set by
+// helpers such as Json, and sets a copy of h instead. Use WithHeader to
+// merge headers rather than replace them.
+func (r *RequestBuilder) ReplaceHeaders(h http.Header) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	r.req.Header = make(http.Header, len(h))
+	return r.WithHeader(h)
+}
+
+// ContentType sets the Content-Type header.
+// Called after Json or PostForm, it overrides the type they set.
+func (r *RequestBuilder) ContentType(mime string) *RequestBuilder {
+	return r.SetHeader("Content-Type", mime)
+}
+
+// IfMatch sets the If-Match header, making the request conditional on
+// the resource still matching etag.
+func (r *RequestBuilder) IfMatch(etag string) *RequestBuilder {
+	return r.SetHeader("If-Match", etag)
+}
+
+// IfNoneMatch sets the If-None-Match header, making the request conditional
+// on the resource not matching etag.
+func (r *RequestBuilder) IfNoneMatch(etag string) *RequestBuilder {
+	return r.SetHeader("If-None-Match", etag)
+}
+
+// IfModifiedSince sets the If-Modified-Since header, making the request
+// conditional on the resource having changed after t.
+func (r *RequestBuilder) IfModifiedSince(t time.Time) *RequestBuilder {
+	return r.SetDateHeader("If-Modified-Since", t)
+}
+
+// SetDateHeader sets the header key to t in the HTTP date format, such as
+// "Mon, 02 Jan 2006 15:04:05 GMT". t is converted to UTC first, as the
+// format requires.
+func (r *RequestBuilder) SetDateHeader(key string, t time.Time) *RequestBuilder {
+	return r.SetHeader(key, t.UTC().Format(http.TimeFormat))
+}
+
+// AcceptLanguage sets the Accept-Language header.
+func (r *RequestBuilder) AcceptLanguage(lang string) *RequestBuilder {
+	return r.SetHeader("Accept-Language", lang)
+}
+
+// ContentLanguage sets the Content-Language header.
+func (r *RequestBuilder) ContentLanguage(lang string) *RequestBuilder {
+	return r.SetHeader("Content-Language", lang)
+}
+
+// Referer sets the Referer header. An unparsable url fails the builder.
+func (r *RequestBuilder) Referer(url string) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	if _, err := urlpkg.Parse(url); err != nil {
+		r.err = err
+		return r
+	}
+	return r.SetHeader("Referer", url)
+}
+
+// RequestID generates a random UUID and sets it as the X-Request-ID header.
+// The same ID is sent on every retry of the request.
+func (r *RequestBuilder) RequestID() *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	id, err := newRequestID()
+	if err != nil {
+		r.err = err
+		return r
+	}
+	return r.RequestIDWith(id)
+}
+
+// RequestIDWith sets the X-Request-ID header to id.
+func (r *RequestBuilder) RequestIDWith(id string) *RequestBuilder {
+	return r.SetHeader("X-Request-ID", id)
+}
+
+// CurrentRequestID returns the value of the X-Request-ID header,
+// or an empty string if none has been set.
+func (r *RequestBuilder) CurrentRequestID() string {
+	if r.err != nil {
+		return ""
+	}
+	return r.req.Header.Get("X-Request-ID")
+}
+
+// ProtoVersion sets the protocol version of the request, e.g. 1, 0 for
+// "HTTP/1.0". The version is seen by validation hooks and custom
+// round trippers, but the net/http transport ignores it: HTTP/1 requests
+// are always written as HTTP/1.1, and HTTP/2 is used when negotiated.
+// Use a custom transport to reach a server with a different version.
+func (r *RequestBuilder) ProtoVersion(major, minor int) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	r.req.Proto = fmt.Sprintf("HTTP/%d.%d", major, minor)
+	r.req.ProtoMajor = major
+	r.req.ProtoMinor = minor
+	return r
+}
+
+// Range sets the Range header to request the bytes from start to end,
+// inclusive. A negative end requests everything from start onwards.
+func (r *RequestBuilder) Range(start, end int64) *RequestBuilder {
+	if end < 0 {
+		return r.SetHeader("Range", fmt.Sprintf("bytes=%d-", start))
+	}
+	return r.SetHeader("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+}
+
 // Form sets form values for the request.
 func (r *RequestBuilder) Form(values urlpkg.Values) *RequestBuilder {
 	if r.err != nil {
@@ -149,7 +564,7 @@ func (r *RequestBuilder) Query(queries map[string]string) *RequestBuilder {
 	for key, value := range queries {
 		query.Add(key, value)
 	}
-	r.req.URL.RawQuery += query.Encode()
+	r.appendQuery(r.queryStyle.encode(query))
 	return r
 }
 
@@ -161,8 +576,77 @@ func (r *RequestBuilder) AddQuery(key, value string) *RequestBuilder {
 	return r.Query(map[string]string{key: value})
 }
 
+// AddQueryValues adds multiple values for a single query parameter,
+// producing a repeated key such as key=a&key=b.
+// Existing query parameters are preserved.
+func (r *RequestBuilder) AddQueryValues(key string, values ...string) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	r.appendQuery(r.queryStyle.encode(urlpkg.Values{key: values}))
+	return r
+}
+
+// AppendRawQuery appends an already-encoded query fragment, such as a
+// precomputed signature parameter, without re-encoding it. It is joined
+// to any existing query with '&'.
+func (r *RequestBuilder) AppendRawQuery(q string) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	r.appendQuery(strings.TrimLeft(q, "?&"))
+	return r
+}
+
+// appendQuery appends an encoded query fragment to the request URL,
+// joining it to any existing query with '&'.
+func (r *RequestBuilder) appendQuery(encoded string) {
+	if encoded == "" {
+		return
+	}
+	if r.req.URL.RawQuery != "" {
+		r.req.URL.RawQuery += "&"
+	}
+	r.req.URL.RawQuery += encoded
+}
+
 // Json sets the body of the request to the JSON representation of v.
 func (r *RequestBuilder) Json(v interface{}) *RequestBuilder {
+	return r.jsonBody(v, "application/json")
+}
+
+// JsonRaw sets the body of the request to already-encoded JSON,
+// sent exactly as given without being marshaled again.
+func (r *RequestBuilder) JsonRaw(raw json.RawMessage) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	r.SetHeader("Content-Type", "application/json")
+	return r.setBody(bytes.NewReader(raw))
+}
+
+// PatchOp is a single JSON Patch (RFC 6902) operation.
+type PatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// JsonMergePatch sets the method to PATCH and the body to the JSON
+// representation of v, sent as a JSON Merge Patch (RFC 7386).
+func (r *RequestBuilder) JsonMergePatch(v interface{}) *RequestBuilder {
+	return r.Patch().jsonBody(v, "application/merge-patch+json")
+}
+
+// JsonPatch sets the method to PATCH and the body to ops,
+// sent as a JSON Patch (RFC 6902).
+func (r *RequestBuilder) JsonPatch(ops []PatchOp) *RequestBuilder {
+	return r.Patch().jsonBody(ops, "application/json-patch+json")
+}
+
+// jsonBody sets the body to the JSON representation of v with the given content type.
+func (r *RequestBuilder) jsonBody(v interface{}, contentType string) *RequestBuilder {
 	if r.err != nil {
 		return r
 	}
@@ -171,8 +655,8 @@ func (r *RequestBuilder) Json(v interface{}) *RequestBuilder {
 		r.err = err
 		return r
 	}
-	r.SetHeader("Content-Type", "application/json")
-	return r.Body(io.NopCloser(bytes.NewBuffer(data)))
+	r.SetHeader("Content-Type", contentType)
+	return r.setBody(bytes.NewReader(data))
 }
 
 // PostForm sets the body of the request to the URL-encoded form data.
@@ -181,24 +665,183 @@ func (r *RequestBuilder) PostForm(values urlpkg.Values) *RequestBuilder {
 		return r
 	}
 	r.SetHeader("Content-Type", "application/x-www-form-urlencoded")
-	body := strings.NewReader(values.Encode())
-	return r.Body(io.NopCloser(body))
+	return r.setBody(strings.NewReader(values.Encode()))
+}
+
+// ErrUnsupportedContentType is returned when a body must be encoded or
+// decoded in a format the package does not know.
+var ErrUnsupportedContentType = errors.New("httpx: unsupported content type")
+
+// Encode sets the body of the request to v, serialized according to the
+// Content-Type header set beforehand: JSON for application/json and
+// "+json" types, XML for application/xml, text/xml and "+xml" types, and a
+// URL-encoded form for application/x-www-form-urlencoded. Without a
+// Content-Type, v is sent as JSON. Form values may be url.Values, a
+// map[string]string or a struct encoded as with QueryStruct.
+func (r *RequestBuilder) Encode(v interface{}) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	contentType := r.req.Header.Get("Content-Type")
+	if contentType == "" {
+		return r.Json(v)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	switch {
+	case isJSONMediaType(mediaType):
+		return r.jsonBody(v, contentType)
+	case isXMLMediaType(mediaType):
+		data, err := xml.Marshal(v)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		return r.setBody(bytes.NewReader(data))
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := formValues(v)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		return r.setBody(strings.NewReader(values.Encode()))
+	default:
+		r.err = fmt.Errorf("%w: %s", ErrUnsupportedContentType, mediaType)
+		return r
+	}
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func isXMLMediaType(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
 }
 
+// formValues converts v into form values for Encode.
+func formValues(v interface{}) (urlpkg.Values, error) {
+	switch v := v.(type) {
+	case urlpkg.Values:
+		return v, nil
+	case map[string][]string:
+		return v, nil
+	case map[string]string:
+		values := urlpkg.Values{}
+		for key, value := range v {
+			values.Set(key, value)
+		}
+		return values, nil
+	}
+	return structValues(v, NestDotted)
+}
+
+// Retry sets the maximum number of attempts, counting the first one, so
+// Retry(3) allows two retries. Retry(0) and Retry(1) both mean a single
+// attempt, which is also the default.
 func (r *RequestBuilder) Retry(retryTimes uint) *RequestBuilder {
 	r.retryTimes = retryTimes
 	return r
 }
 
+// NoRetry makes the request a single attempt, overriding an earlier Retry.
+// Other retry settings such as the backoff are kept, so a later Retry
+// re-enables them.
+func (r *RequestBuilder) NoRetry() *RequestBuilder {
+	return r.Retry(0)
+}
+
+// NoBodyReplay forbids sending the request body more than once, as a
+// safeguard for non-idempotent operations such as payments: when a request
+// with a body fails in a way Retry would retry, Do returns an error wrapping
+// ErrBodyNotReplayable instead of sending the body again. Redirects that
+// would resend the body, such as 307 and 308, are not followed either.
+func (r *RequestBuilder) NoBodyReplay() *RequestBuilder {
+	r.noBodyReplay = true
+	return r
+}
+
+// Validate registers fn to check the request when it is built.
+// If fn returns an error, building fails and the request is never sent.
+func (r *RequestBuilder) Validate(fn func(*http.Request) error) *RequestBuilder {
+	r.hooks = append(r.hooks, fn)
+	return r
+}
+
+// ErrInsecureScheme is returned when RequireHTTPS is set and the request URL is not https.
+var ErrInsecureScheme = errors.New("httpx: URL scheme must be https")
+
+// RequireHTTPS makes building the request fail with ErrInsecureScheme
+// unless the URL uses https, guarding against sending credentials in
+// plain text. The URL is checked after base URL resolution.
+func (r *RequestBuilder) RequireHTTPS() *RequestBuilder {
+	return r.Validate(func(req *http.Request) error {
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("%w: %s", ErrInsecureScheme, req.URL.Redacted())
+		}
+		return nil
+	})
+}
+
+// ErrBodyNotAllowed is returned when StrictMethodBodies is set and a GET
+// or HEAD request has a body.
+var ErrBodyNotAllowed = errors.New("httpx: request method does not allow a body")
+
+// StrictMethodBodies makes building the request fail with ErrBodyNotAllowed
+// if a GET or HEAD request has a body. Such bodies have no defined meaning
+// and are often dropped by proxies and caches, so sending one is usually a
+// mistake, such as forgetting to call Post.
+func (r *RequestBuilder) StrictMethodBodies() *RequestBuilder {
+	return r.Validate(func(req *http.Request) error {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			return nil
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			return fmt.Errorf("%w: %s", ErrBodyNotAllowed, req.Method)
+		}
+		return nil
+	})
+}
+
 // BuildWithContext builds the request with the provided context.
 func (r *RequestBuilder) BuildWithContext(ctx context.Context) (*http.Request, error) {
 	if r.err != nil {
 		return nil, r.err
 	}
-	if ctx == context.Background() {
-		return r.req, nil
+	if r.noContentLength && r.req.Body != nil && r.req.Body != http.NoBody {
+		r.req.ContentLength = -1
+	}
+	if len(r.metadata) > 0 {
+		ctx = context.WithValue(ctx, metadataKey{}, r.metadata)
+	}
+	req := r.req
+	if ctx != context.Background() {
+		req = req.WithContext(ctx)
+	}
+	for _, hook := range r.hooks {
+		if err := hook(req); err != nil {
+			r.err = err
+			return nil, err
+		}
+	}
+	if r.uploadProgress != nil && req.Body != nil && req.Body != http.NoBody {
+		if req == r.req {
+			req = req.WithContext(ctx)
+		}
+		r.trackUpload(req)
 	}
-	return r.req.WithContext(ctx), nil
+	// GetBody is only dropped from the outgoing request, after hooks such
+	// as Sign have read the body through it.
+	if r.noBodyReplay && req.GetBody != nil {
+		if req == r.req {
+			req = req.WithContext(ctx)
+		}
+		req.GetBody = nil
+	}
+	return req, nil
 }
 
 // Build builds the request with a background context.
@@ -207,27 +850,158 @@ func (r *RequestBuilder) Build() (*http.Request, error) {
 }
 
 // Do send the request and returns the response.
-func (r *RequestBuilder) Do() (resp *http.Response, err error) {
-	req, err := r.Build()
-	if err != nil {
-		return nil, err
+func (r *RequestBuilder) Do() (*http.Response, error) {
+	return r.DoWithContext(context.Background())
+}
+
+// DoWithContext sends the request with the provided context, which
+// also bounds the time spent waiting between retries. If the response
+// fails an expectation such as ExpectContentType, it is returned with its
+// body closed, together with the error.
+func (r *RequestBuilder) DoWithContext(ctx context.Context) (*http.Response, error) {
+	resp, _, err := r.doStats(ctx, nil)
+	return resp, err
+}
+
+// doStats is DoWithContext that also returns the stats of the call.
+// If trace is not nil, the time to first byte is measured with it.
+func (r *RequestBuilder) doStats(ctx context.Context, trace *firstByteTrace) (*http.Response, Stats, error) {
+	if trace != nil {
+		ctx = trace.withContext(ctx)
 	}
+	start := time.Now()
+	ctx, cancel := r.withDeadline(ctx, start)
+	resp, attempts, err := r.send(ctx)
+	if err == nil {
+		err = r.checkExpectations(resp)
+	}
+	if cancel != nil {
+		if err == nil {
+			// The body is read under the deadline too, so release the
+			// context only once the caller is done with it.
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		} else {
+			cancel()
+		}
+	}
+	stats := r.stats(resp, attempts, time.Since(start), err)
+	if trace != nil {
+		stats.TimeToFirstByte = trace.timeToFirstByte()
+	}
+	if r.onComplete != nil {
+		r.onComplete(stats)
+	}
+	return resp, stats, err
+}
 
-	var client = r.client
-	if client == nil {
-		client = http.DefaultClient
+// send builds the request and sends it, retrying as configured.
+// It returns the final outcome and the number of attempts made.
+func (r *RequestBuilder) send(ctx context.Context) (resp *http.Response, attempts int, err error) {
+	req, err := r.BuildWithContext(ctx)
+	if err != nil {
+		return nil, 0, err
 	}
+	for _, fn := range r.beforeSend {
+		if err = fn(req); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	client := r.httpClient()
 
 	retryTimes := r.retryTimes
 	if retryTimes == 0 {
 		retryTimes = 1
 	}
 
-	for i := 0; i < int(retryTimes); i++ {
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
 		resp, err = client.Do(req)
-		if err == nil {
-			return resp, nil
+		r.logAttempt(ctx, req, attempt, resp, err, time.Since(start))
+		if attempt >= int(retryTimes) || !r.shouldRetry(resp, err) {
+			return resp, attempt, err
+		}
+		// Once ctx is done every further attempt fails without being sent,
+		// so it must not spend the retry budget or be reported to OnRetry.
+		if ctx.Err() != nil {
+			return resp, attempt, err
+		}
+		if r.retryBudget != nil && !r.retryBudget.Allow() {
+			return resp, attempt, err
+		}
+		// The previous attempt consumed the body; without a fresh copy
+		// the retry would send an empty one.
+		if rewindErr := rewindBody(req); rewindErr != nil {
+			if !r.noBodyReplay {
+				return resp, attempt, err
+			}
+			cause := err
+			if resp != nil {
+				cause = errors.New(resp.Status)
+				closeBody(resp.Body)
+			}
+			return nil, attempt, fmt.Errorf("%w: attempt %d failed: %v", rewindErr, attempt, cause)
+		}
+		if r.onRetry != nil {
+			r.onRetry(attempt, resp, err)
+		}
+		if resp != nil {
+			closeBody(resp.Body)
 		}
+		if r.backoff != nil {
+			if err = r.sleepFunc()(ctx, r.backoff.NextDelay(attempt)); err != nil {
+				return nil, attempt, err
+			}
+		}
+	}
+}
+
+// Timeout limits the time a call to Do may take, including retries and
+// reading the response body, to d from when it starts.
+func (r *RequestBuilder) Timeout(d time.Duration) *RequestBuilder {
+	r.timeout = d
+	return r
+}
+
+// Deadline makes a call to Do fail once t has passed, including retries
+// and reading the response body, for callers with an absolute deadline
+// such as a budget computed upstream. With Timeout as well, the earlier
+// of the two applies.
+func (r *RequestBuilder) Deadline(t time.Time) *RequestBuilder {
+	r.deadline = t
+	return r
+}
+
+// withDeadline applies the Timeout and Deadline settings to ctx for a
+// call started at start. cancel is nil if neither is set.
+func (r *RequestBuilder) withDeadline(ctx context.Context, start time.Time) (context.Context, context.CancelFunc) {
+	deadline := r.deadline
+	if r.timeout > 0 {
+		if d := start.Add(r.timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	if deadline.IsZero() {
+		return ctx, nil
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// DoTimed sends the request like Do and also returns the elapsed time,
+// including any retries.
+func (r *RequestBuilder) DoTimed() (*http.Response, time.Duration, error) {
+	start := time.Now()
+	resp, err := r.Do()
+	return resp, time.Since(start), err
+}
+
+// newRequestID returns a random (version 4) UUID.
+func newRequestID() (string, error) {
+	var b [16]byte
+	if err := randomBytes(b[:]); err != nil {
+		return "", err
 	}
-	return nil, err
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
 }
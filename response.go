@@ -0,0 +1,688 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	urlpkg "net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPError is returned by the decode helpers when the server
+// responds with a non-2xx status code.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	// Body holds the raw response body.
+	Body []byte
+	// Decoded holds the value registered with ErrorBody after the
+	// body has been unmarshalled into it, or nil if none was registered
+	// or the body could not be decoded.
+	Decoded interface{}
+	// Err holds the error returned for the status code by the function
+	// registered with StatusErrorMapper, or nil.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("httpx: unexpected status %s: %v", e.Status, e.Err)
+	}
+	return fmt.Sprintf("httpx: unexpected status %s", e.Status)
+}
+
+// Unwrap returns Err, so errors.Is and errors.As see the mapped error.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// StatusErrorMapper registers fn to turn non-2xx status codes into domain
+// errors, such as 404 into ErrNotFound. The error returned by fn is wrapped
+// by the *HTTPError of the decode and assertion helpers, so errors.Is
+// matches it while the response details stay available. fn returns nil
+// for codes it does not map.
+func (r *RequestBuilder) StatusErrorMapper(fn func(code int) error) *RequestBuilder {
+	r.statusErrorMapper = fn
+	return r
+}
+
+// ErrorBody registers v to receive the JSON body of non-2xx responses.
+// The decoded value is attached to the returned HTTPError as Decoded.
+func (r *RequestBuilder) ErrorBody(v interface{}) *RequestBuilder {
+	r.errorBody = v
+	return r
+}
+
+// DoJSON sends the request and decodes the JSON response body into v.
+// It returns an *HTTPError if the response status is not 2xx.
+func (r *RequestBuilder) DoJSON(v interface{}) error {
+	return r.doRead(func(resp *http.Response) error {
+		return r.newDecoder(resp.Body).Decode(v)
+	})
+}
+
+// Decode sends the request and decodes the response body into v as JSON
+// or XML, chosen by the response Content-Type as in Encode. Other types
+// fail with ErrUnsupportedContentType. Non-2xx responses are reported as
+// *HTTPError.
+func (r *RequestBuilder) Decode(v interface{}) error {
+	return r.doRead(func(resp *http.Response) error {
+		switch mediaType := (&Response{Response: resp}).ContentType(); {
+		case isJSONMediaType(mediaType):
+			return r.newDecoder(resp.Body).Decode(v)
+		case isXMLMediaType(mediaType):
+			return xml.NewDecoder(resp.Body).Decode(v)
+		default:
+			return fmt.Errorf("%w: %q", ErrUnsupportedContentType, resp.Header.Get("Content-Type"))
+		}
+	})
+}
+
+// DoJSONOrError sends the request and decodes the JSON response body into
+// success if the status is 2xx, or into failure otherwise, for APIs with
+// distinct success and error schemas. ok reports which one was decoded.
+// A non-2xx body that is not valid JSON for failure is reported as an
+// *HTTPError. The body is always closed.
+func (r *RequestBuilder) DoJSONOrError(success, failure interface{}) (ok bool, err error) {
+	resp, err := r.Do()
+	if err != nil {
+		return false, err
+	}
+	defer closeBody(resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, r.newDecoder(resp.Body).Decode(success)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+	if json.Unmarshal(body, failure) != nil {
+		return false, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+	}
+	return false, nil
+}
+
+// DoJSONArray sends the request and streams a response body holding a
+// top-level JSON array, calling fn once per element without loading the
+// whole array into memory. fn receives a decode function that decodes the
+// current element into its argument; an element fn does not decode is
+// skipped. An error returned by fn stops the iteration and is returned.
+func (r *RequestBuilder) DoJSONArray(fn func(decode func(v interface{}) error) error) error {
+	return r.doRead(func(resp *http.Response) error {
+		decoder := r.newDecoder(resp.Body)
+		tok, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			return fmt.Errorf("httpx: expected a JSON array, got %v", tok)
+		}
+		for decoder.More() {
+			decoded := false
+			decode := func(v interface{}) error {
+				if decoded {
+					return errors.New("httpx: array element already decoded")
+				}
+				decoded = true
+				return decoder.Decode(v)
+			}
+			if err = fn(decode); err != nil {
+				return err
+			}
+			if !decoded {
+				if err = decoder.Decode(&json.RawMessage{}); err != nil {
+					return err
+				}
+			}
+		}
+		_, err = decoder.Token()
+		return err
+	})
+}
+
+// StrictJSON makes the response decode helpers reject JSON objects with
+// fields that do not match the destination, catching typos and schema
+// drift. Request bodies are encoded as usual.
+func (r *RequestBuilder) StrictJSON() *RequestBuilder {
+	r.strictJSON = true
+	return r
+}
+
+// UseNumber makes the response decode helpers decode JSON numbers stored
+// in interface{} values as json.Number rather than float64, preserving
+// integers, such as large IDs, that float64 cannot represent exactly.
+func (r *RequestBuilder) UseNumber() *RequestBuilder {
+	r.useNumber = true
+	return r
+}
+
+// newDecoder returns a JSON decoder for a response body, configured by
+// the builder's decode options.
+func (r *RequestBuilder) newDecoder(body io.Reader) *json.Decoder {
+	decoder := json.NewDecoder(body)
+	if r == nil {
+		// A Response built by the caller has no builder settings to apply.
+		return decoder
+	}
+	if r.strictJSON {
+		decoder.DisallowUnknownFields()
+	}
+	if r.useNumber {
+		decoder.UseNumber()
+	}
+	return decoder
+}
+
+// RetryOnBodyError makes the decode helpers re-issue the request when the
+// connection fails while the response body is being read, for instance
+// with io.ErrUnexpectedEOF. The initial response arrived, so such failures
+// are not covered by Retry alone. The request is re-issued at most as many
+// times as Retry allows, and only if its method is idempotent and its body
+// can be replayed. Each re-issue is a new call to Do: BeforeSend hooks run
+// again for it, and OnComplete reports it separately.
+func (r *RequestBuilder) RetryOnBodyError() *RequestBuilder {
+	r.retryOnBodyError = true
+	return r
+}
+
+// doRead sends the request, checks the response status and passes the
+// response to read, draining and closing the body afterwards.
+func (r *RequestBuilder) doRead(read func(resp *http.Response) error) error {
+	for attempt := 1; ; attempt++ {
+		resp, err := r.Do()
+		if err != nil {
+			return err
+		}
+		body := &bodyErrorReader{ReadCloser: resp.Body}
+		resp.Body = body
+		if err = r.checkStatus(resp); err == nil {
+			err = read(resp)
+		}
+		closeBody(body)
+		if err == nil || body.err == nil || !r.canReissue(attempt) {
+			return err
+		}
+		if err = rewindBody(r.req); err != nil {
+			return err
+		}
+	}
+}
+
+// canReissue reports whether a request whose body read failed on the
+// given attempt may be sent again.
+func (r *RequestBuilder) canReissue(attempt int) bool {
+	if !r.retryOnBodyError || attempt >= int(r.retryTimes) {
+		return false
+	}
+	switch r.req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	return r.req.Body == nil || r.req.Body == http.NoBody || (r.req.GetBody != nil && !r.noBodyReplay)
+}
+
+// rewindBody replaces a consumed request body with a fresh copy.
+func rewindBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody == nil {
+		return ErrBodyNotReplayable
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+// bodyErrorReader records the first error, other than io.EOF,
+// returned while reading a response body.
+type bodyErrorReader struct {
+	io.ReadCloser
+	err error
+}
+
+func (b *bodyErrorReader) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.err == nil {
+		b.err = err
+	}
+	return n, err
+}
+
+// CopyTo sends the request and streams the response body into w,
+// returning the response and the number of bytes copied.
+// The body is closed before CopyTo returns. Non-2xx responses are
+// reported as *HTTPError and nothing is written to w.
+func (r *RequestBuilder) CopyTo(w io.Writer) (*http.Response, int64, error) {
+	resp, err := r.Do()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer closeBody(resp.Body)
+	if err = r.checkStatus(resp); err != nil {
+		return resp, 0, err
+	}
+	var body io.Reader = resp.Body
+	if r.decompressDownload {
+		if body, err = gunzipIfCompressed(body); err != nil {
+			return resp, 0, err
+		}
+	}
+	n, err := io.Copy(w, body)
+	return resp, n, err
+}
+
+// DoWithChecksum sends the request and reads the response body while
+// feeding it to h, returning the hex-encoded digest, e.g. to verify a
+// download against a published SHA-256. The body is replaced with an
+// in-memory copy that can still be read; to avoid holding a large download
+// in memory, use CopyTo with io.MultiWriter(file, h) instead. Non-2xx
+// responses are reported as *HTTPError and are not hashed.
+func (r *RequestBuilder) DoWithChecksum(h hash.Hash) (string, *http.Response, error) {
+	resp, err := r.Do()
+	if err != nil {
+		return "", nil, err
+	}
+	defer closeBody(resp.Body)
+	if err = r.checkStatus(resp); err != nil {
+		return "", resp, err
+	}
+	var buf bytes.Buffer
+	if _, err = io.Copy(io.MultiWriter(&buf, h), resp.Body); err != nil {
+		return "", resp, err
+	}
+	resp.Body = &bufferedBody{Reader: bytes.NewReader(buf.Bytes())}
+	return hex.EncodeToString(h.Sum(nil)), resp, nil
+}
+
+// DecompressOnDownload makes CopyTo decompress gzip data detected by
+// its magic bytes, even when the server sent no Content-Encoding header,
+// as is common for .gz files.
+func (r *RequestBuilder) DecompressOnDownload() *RequestBuilder {
+	r.decompressDownload = true
+	return r
+}
+
+// gunzipIfCompressed returns a reader decompressing body if it starts
+// with the gzip magic bytes, or reading it unchanged otherwise.
+func gunzipIfCompressed(body io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(body)
+	magic, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// DoInto sends the request built by b and decodes the JSON response body
+// into a value of type T. Non-2xx responses are reported as *HTTPError.
+func DoInto[T any](b *RequestBuilder) (T, error) {
+	var v T
+	err := b.DoJSON(&v)
+	return v, err
+}
+
+// Ping sends the request as a HEAD request and reports whether the server
+// answered with a 2xx status, which makes it suitable for readiness checks.
+// Other statuses are reported as *HTTPError. The client timeout applies.
+func (r *RequestBuilder) Ping() error {
+	return r.PingContext(context.Background())
+}
+
+// PingContext is like Ping but sends the request with the given context.
+func (r *RequestBuilder) PingContext(ctx context.Context) error {
+	resp, err := r.Head().DoWithContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp.Body)
+	return r.checkStatus(resp)
+}
+
+// maxDrainBytes caps how much of an unread body closeBody discards. Past
+// this it is cheaper to drop the connection than to keep reading.
+const maxDrainBytes = 1 << 20
+
+// closeBody discards the rest of body and closes it, so the underlying
+// connection can be reused even if the caller stopped reading early.
+func closeBody(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainBytes))
+	_ = body.Close()
+}
+
+// checkStatus returns an *HTTPError if the response status is not 2xx.
+func (r *RequestBuilder) checkStatus(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	httpErr := &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+	if r.statusErrorMapper != nil {
+		httpErr.Err = r.statusErrorMapper(resp.StatusCode)
+	}
+	if r.errorBody != nil && json.Unmarshal(body, r.errorBody) == nil {
+		httpErr.Decoded = r.errorBody
+	}
+	return httpErr
+}
+
+// ErrUnexpectedContentType is returned when a response does not have the
+// media type set with ExpectContentType.
+var ErrUnexpectedContentType = errors.New("httpx: unexpected content type")
+
+// ExpectContentType makes Do fail with ErrUnexpectedContentType unless the
+// response media type is mediaType, ignoring parameters such as charset.
+// This catches an HTML error page served where JSON was expected.
+func (r *RequestBuilder) ExpectContentType(mediaType string) *RequestBuilder {
+	return r.expect(func(resp *http.Response) error {
+		got := (&Response{Response: resp}).ContentType()
+		if !strings.EqualFold(got, mediaType) {
+			return fmt.Errorf("%w: got %q, want %q", ErrUnexpectedContentType, got, mediaType)
+		}
+		return nil
+	})
+}
+
+// ExpectSuccess makes Do fail with an *HTTPError when the response status
+// is not 2xx.
+func (r *RequestBuilder) ExpectSuccess() *RequestBuilder {
+	return r.expect(r.checkStatus)
+}
+
+// DoStreaming sends the request for a response that is consumed as a
+// stream, such as server-sent events, and returns a cancel function along
+// with it. Calling cancel aborts the request and tears down its connection,
+// so a caller that stops reading early does not leave it hanging; closing
+// the body cancels as well. cancel is nil when err is not.
+func (r *RequestBuilder) DoStreaming() (*http.Response, context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	resp, err := r.DoWithContext(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, cancel, nil
+}
+
+// cancelBody cancels the request context once the body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// DoAndClose sends the request and discards the response, draining and
+// closing its body. It suits webhooks and notifications, where only failure
+// matters: combine it with ExpectSuccess to also treat non-2xx responses as
+// errors.
+func (r *RequestBuilder) DoAndClose() error {
+	resp, err := r.Do()
+	if err != nil {
+		return err
+	}
+	closeBody(resp.Body)
+	return nil
+}
+
+// maxBufferedBytes caps the size of a body read into memory by BufferResponse.
+const maxBufferedBytes = 32 << 20
+
+// ErrResponseTooLarge is returned when a body buffered by BufferResponse
+// exceeds the size limit.
+var ErrResponseTooLarge = errors.New("httpx: response body too large to buffer")
+
+// BufferResponse makes Do read the whole response body into memory and
+// replace it with one that can be read any number of times: closing it or
+// seeking to the start rewinds it. This is handy when a body is decoded and
+// then logged. Bodies larger than 32 MiB fail with ErrResponseTooLarge.
+func (r *RequestBuilder) BufferResponse() *RequestBuilder {
+	return r.expect(func(resp *http.Response) error {
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxBufferedBytes+1))
+		if err != nil {
+			return err
+		}
+		if len(data) > maxBufferedBytes {
+			return ErrResponseTooLarge
+		}
+		_ = resp.Body.Close()
+		resp.Body = &bufferedBody{Reader: bytes.NewReader(data)}
+		return nil
+	})
+}
+
+// bufferedBody is an in-memory response body that rewinds on Close.
+type bufferedBody struct {
+	*bytes.Reader
+}
+
+func (b *bufferedBody) Close() error {
+	_, err := b.Seek(0, io.SeekStart)
+	return err
+}
+
+// expect registers check to run against the response once it arrives.
+func (r *RequestBuilder) expect(check func(resp *http.Response) error) *RequestBuilder {
+	r.expectations = append(r.expectations, check)
+	return r
+}
+
+// checkExpectations runs the registered checks against resp. On failure
+// the body is closed, since the caller will not read it.
+func (r *RequestBuilder) checkExpectations(resp *http.Response) error {
+	for _, check := range r.expectations {
+		if err := check(resp); err != nil {
+			closeBody(resp.Body)
+			return err
+		}
+	}
+	return nil
+}
+
+// Cookies returns the cookies set by the Set-Cookie headers of resp.
+func Cookies(resp *http.Response) []*http.Cookie {
+	return resp.Cookies()
+}
+
+// CookieValue returns the value of the cookie named name set by resp,
+// and whether it was found. If the cookie is set more than once, the
+// last value wins, as in a browser.
+func CookieValue(resp *http.Response, name string) (string, bool) {
+	var value string
+	var found bool
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == name {
+			value, found = cookie.Value, true
+		}
+	}
+	return value, found
+}
+
+// ErrHeaderMissing is returned by Response accessors when the header is absent.
+var ErrHeaderMissing = errors.New("httpx: header missing")
+
+// Response wraps an http.Response with typed accessors for common headers.
+type Response struct {
+	*http.Response
+
+	// err is the first error of a DoResponse chain.
+	err     error
+	builder *RequestBuilder
+}
+
+// Execute sends the request and wraps the response.
+func (r *RequestBuilder) Execute() (*Response, error) {
+	resp, err := r.Do()
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Response: resp, builder: r}, nil
+}
+
+// ErrAssertionFailed is returned when a Response assertion does not hold.
+var ErrAssertionFailed = errors.New("httpx: response assertion failed")
+
+// DoResponse sends the request and returns a Response for chaining checks:
+//
+//	err := builder.DoResponse().
+//		AssertStatus(http.StatusOK).
+//		AssertHeader("Content-Type", "application/json").
+//		JSON(&v)
+//
+// The chain records the first error, whether from sending the request or
+// from a failed assertion, and later steps do nothing once it is set.
+// Finish the chain with JSON or Err; the body is closed on failure or by
+// JSON, otherwise the caller must close it.
+func (r *RequestBuilder) DoResponse() *Response {
+	resp, err := r.Do()
+	return &Response{Response: resp, err: err, builder: r}
+}
+
+// AssertStatus fails the chain with ErrAssertionFailed unless the status
+// code is one of codes. The error also wraps the error StatusErrorMapper
+// maps a non-2xx code to.
+func (resp *Response) AssertStatus(codes ...int) *Response {
+	if resp.err != nil {
+		return resp
+	}
+	for _, code := range codes {
+		if resp.StatusCode == code {
+			return resp
+		}
+	}
+	err := fmt.Errorf("%w: status %d, want %v", ErrAssertionFailed, resp.StatusCode, codes)
+	if mapped := resp.mappedStatusError(); mapped != nil {
+		err = fmt.Errorf("%w: %w", err, mapped)
+	}
+	return resp.fail(err)
+}
+
+// mappedStatusError returns the error the builder's StatusErrorMapper maps
+// a non-2xx status code to, if any.
+func (resp *Response) mappedStatusError() error {
+	if resp.builder == nil || resp.builder.statusErrorMapper == nil ||
+		(resp.StatusCode >= 200 && resp.StatusCode < 300) {
+		return nil
+	}
+	return resp.builder.statusErrorMapper(resp.StatusCode)
+}
+
+// AssertHeader fails the chain with ErrAssertionFailed unless the header
+// key has the given value. Content-Type is compared by media type only,
+// ignoring parameters such as charset.
+func (resp *Response) AssertHeader(key, value string) *Response {
+	if resp.err != nil {
+		return resp
+	}
+	got := resp.Header.Get(key)
+	if http.CanonicalHeaderKey(key) == "Content-Type" {
+		got = resp.ContentType()
+	}
+	if !strings.EqualFold(got, value) {
+		return resp.fail(fmt.Errorf("%w: header %s is %q, want %q", ErrAssertionFailed, key, got, value))
+	}
+	return resp
+}
+
+// JSON decodes the body into v, closes it and returns the first error
+// of the chain.
+func (resp *Response) JSON(v interface{}) error {
+	if resp.err != nil {
+		return resp.err
+	}
+	defer closeBody(resp.Body)
+	if err := resp.builder.newDecoder(resp.Body).Decode(v); err != nil {
+		resp.err = err
+	}
+	return resp.err
+}
+
+// Err returns the first error of the chain.
+func (resp *Response) Err() error {
+	return resp.err
+}
+
+func (resp *Response) fail(err error) *Response {
+	resp.err = err
+	closeBody(resp.Body)
+	return resp
+}
+
+// ContentLength returns the length of the body from the Content-Length
+// header, or -1 if it is unknown or malformed.
+func (resp *Response) ContentLength() int64 {
+	value := resp.Header.Get("Content-Length")
+	if value == "" {
+		return resp.Response.ContentLength
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || n < 0 {
+		return -1
+	}
+	return n
+}
+
+// ContentType returns the media type of the Content-Type header, lower-cased
+// and without parameters such as charset. It returns an empty string if the
+// header is absent or malformed.
+func (resp *Response) ContentType() string {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// Location returns the URL of the Location header, resolved against the
+// request URL. It returns http.ErrNoLocation if the header is absent.
+func (resp *Response) Location() (*urlpkg.URL, error) {
+	return resp.Response.Location()
+}
+
+// FinalURL returns the URL of the request that produced the response,
+// which is the last hop when redirects were followed, e.g. the target of
+// a shortened link. It returns nil if the response has no request.
+func (resp *Response) FinalURL() *urlpkg.URL {
+	if resp.Request == nil {
+		return nil
+	}
+	return resp.Request.URL
+}
+
+// LastModified parses the Last-Modified header in any of the time formats
+// allowed by HTTP.
+func (resp *Response) LastModified() (time.Time, error) {
+	value := resp.Header.Get("Last-Modified")
+	if value == "" {
+		return time.Time{}, fmt.Errorf("%w: Last-Modified", ErrHeaderMissing)
+	}
+	return http.ParseTime(value)
+}
@@ -0,0 +1,716 @@
+package httpx_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestRequestBuilder_DoJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"httpx"}`))
+	}))
+	defer server.Close()
+
+	var result struct {
+		Name string `json:"name"`
+	}
+	err := httpx.New(server.URL).DoJSON(&result)
+	require.NoError(t, err)
+	assert.Equal(t, "httpx", result.Name)
+}
+
+func TestRequestBuilder_ErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":"invalid_argument","message":"name is required"}`))
+	}))
+	defer server.Close()
+
+	type apiError struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	var result map[string]interface{}
+	err := httpx.New(server.URL).ErrorBody(&apiError{}).DoJSON(&result)
+	require.Error(t, err)
+
+	var httpErr *httpx.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusBadRequest, httpErr.StatusCode)
+	require.IsType(t, &apiError{}, httpErr.Decoded)
+	decoded := httpErr.Decoded.(*apiError)
+	assert.Equal(t, "invalid_argument", decoded.Code)
+	assert.Equal(t, "name is required", decoded.Message)
+	assert.Nil(t, result)
+}
+
+func TestRequestBuilder_DoJSON_DrainsBody(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// Only the first object is decoded; the rest must still be drained.
+		w.Write([]byte(`{"name":"first"}` + strings.Repeat(" ", 512<<10) + `{"name":"second"}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	transport := &http.Transport{MaxConnsPerHost: 1}
+	defer transport.CloseIdleConnections()
+
+	for i := 0; i < 2; i++ {
+		var result struct {
+			Name string `json:"name"`
+		}
+		err := httpx.New(server.URL).RoundTripper(transport).DoJSON(&result)
+		require.NoError(t, err)
+		assert.Equal(t, "first", result.Name)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+}
+
+func TestDoInto(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":7,"name":"httpx"}`))
+	}))
+	defer server.Close()
+
+	type repo struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+
+	result, err := httpx.DoInto[repo](httpx.New(server.URL))
+	require.NoError(t, err)
+	assert.Equal(t, repo{ID: 7, Name: "httpx"}, result)
+
+	_, err = httpx.DoInto[repo](httpx.New(server.URL + "/missing"))
+	var httpErr *httpx.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+}
+
+func TestRequestBuilder_CopyTo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("streamed content"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	resp, n, err := httpx.New(server.URL).CopyTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int64(len("streamed content")), n)
+	assert.Equal(t, "streamed content", buf.String())
+
+	buf.Reset()
+	_, n, err = httpx.New(server.URL + "/missing").CopyTo(&buf)
+	var httpErr *httpx.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	assert.Zero(t, n)
+	assert.Zero(t, buf.Len())
+}
+
+func TestRequestBuilder_DoWithChecksum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("hello world"))
+	}))
+	defer server.Close()
+
+	sum, resp, err := httpx.New(server.URL).DoWithChecksum(sha256.New())
+	require.NoError(t, err)
+	assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", sum)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(body))
+
+	_, _, err = httpx.New(server.URL + "/missing").DoWithChecksum(sha256.New())
+	var httpErr *httpx.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+}
+
+func TestRequestBuilder_DecompressOnDownload(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("decompressed content"))
+	require.NoError(t, gz.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// No Content-Encoding: the file itself is gzip.
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(compressed.Bytes())
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	_, _, err := httpx.New(server.URL).DecompressOnDownload().CopyTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "decompressed content", buf.String())
+
+	buf.Reset()
+	_, _, err = httpx.New(server.URL).CopyTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, compressed.Bytes(), buf.Bytes())
+}
+
+func TestRequestBuilder_Execute(t *testing.T) {
+	modified := time.Date(2023, 10, 21, 7, 28, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "Application/JSON; charset=utf-8")
+		w.Header().Set("Location", "/items/1")
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL + "/items").Post().Execute()
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, int64(8), resp.ContentLength())
+	assert.Equal(t, "application/json", resp.ContentType())
+
+	location, err := resp.Location()
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/items/1", location.String())
+
+	lastModified, err := resp.LastModified()
+	require.NoError(t, err)
+	assert.True(t, modified.Equal(lastModified))
+}
+
+func TestResponse_JSONWithoutBuilder(t *testing.T) {
+	resp := &httpx.Response{Response: &http.Response{
+		Header: http.Header{},
+		Body:   io.NopCloser(strings.NewReader(`{"id":1}`)),
+	}}
+	var v struct{ ID int }
+	require.NoError(t, resp.JSON(&v))
+	assert.Equal(t, 1, v.ID)
+}
+
+func TestResponse_MalformedHeaders(t *testing.T) {
+	resp := &httpx.Response{Response: &http.Response{Header: http.Header{
+		"Content-Length": {"ten"},
+		"Content-Type":   {"text/html; charset"},
+		"Last-Modified":  {"yesterday"},
+		"Location":       {"http://[::1"},
+	}}}
+	assert.Equal(t, int64(-1), resp.ContentLength())
+	assert.Equal(t, "", resp.ContentType())
+	_, err := resp.LastModified()
+	assert.Error(t, err)
+	_, err = resp.Location()
+	assert.Error(t, err)
+
+	empty := &httpx.Response{Response: &http.Response{Header: http.Header{}, ContentLength: -1}}
+	assert.Equal(t, int64(-1), empty.ContentLength())
+	assert.Equal(t, "", empty.ContentType())
+	_, err = empty.LastModified()
+	assert.ErrorIs(t, err, httpx.ErrHeaderMissing)
+	_, err = empty.Location()
+	assert.ErrorIs(t, err, http.ErrNoLocation)
+}
+
+func TestRequestBuilder_RetryOnBodyError(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1)%2 == 1 {
+			// Promise more than is sent, then drop the connection.
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if !assert.NoError(t, err) {
+				return
+			}
+			buf.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 100\r\n\r\n{\"name\":")
+			buf.Flush()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(`{"name":"httpx"}`))
+	}))
+	defer server.Close()
+
+	var result struct {
+		Name string `json:"name"`
+	}
+	err := httpx.New(server.URL).DoJSON(&result)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	atomic.StoreInt32(&attempts, 0)
+	var sends, completions int
+	err = httpx.New(server.URL).Retry(2).RetryOnBodyError().
+		BeforeSend(func(*http.Request) error { sends++; return nil }).
+		OnComplete(func(httpx.Stats) { completions++ }).
+		DoJSON(&result)
+	require.NoError(t, err)
+	assert.Equal(t, "httpx", result.Name)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	// Each re-issue is a new send.
+	assert.Equal(t, 2, sends)
+	assert.Equal(t, 2, completions)
+
+	// Non-idempotent requests are never re-issued.
+	atomic.StoreInt32(&attempts, 0)
+	err = httpx.New(server.URL).Post().Retry(2).RetryOnBodyError().DoJSON(&result)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestRequestBuilder_Ping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		if r.URL.Path == "/healthy" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	assert.NoError(t, httpx.New(server.URL+"/healthy").Ping())
+
+	err := httpx.New(server.URL + "/unhealthy").Ping()
+	var httpErr *httpx.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusServiceUnavailable, httpErr.StatusCode)
+}
+
+func TestRequestBuilder_PingContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := httpx.New(server.URL).PingContext(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRequestBuilder_StrictJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"httpx","nmae":"typo"}`))
+	}))
+	defer server.Close()
+
+	var v struct {
+		Name string `json:"name"`
+	}
+	require.NoError(t, httpx.New(server.URL).DoJSON(&v))
+	assert.Equal(t, "httpx", v.Name)
+
+	err := httpx.New(server.URL).StrictJSON().DoJSON(&v)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown field "nmae"`)
+}
+
+func TestRequestBuilder_ExpectContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json" {
+			w.Header().Set("Content-Type", "Application/JSON; charset=utf-8")
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html>maintenance</html>"))
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL + "/json").ExpectContentType("application/json").Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = httpx.New(server.URL + "/html").ExpectContentType("application/json").Do()
+	assert.ErrorIs(t, err, httpx.ErrUnexpectedContentType)
+	assert.Contains(t, err.Error(), `got "text/html"`)
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestRequestBuilder_BufferResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":7}`))
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).BufferResponse().Do()
+	require.NoError(t, err)
+
+	var v struct{ ID int }
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&v))
+	assert.Equal(t, 7, v.ID)
+	require.NoError(t, resp.Body.Close())
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":7}`, string(body))
+	_, ok := resp.Body.(io.ReadSeekCloser)
+	assert.True(t, ok)
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRequestBuilder_DoAndClose(t *testing.T) {
+	var body *closeRecorder
+	status := http.StatusAccepted
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body = &closeRecorder{Reader: strings.NewReader("ignored")}
+		return &http.Response{StatusCode: status, Body: body}, nil
+	})
+
+	require.NoError(t, httpx.New("http://example.com").Post().RoundTripper(transport).DoAndClose())
+	assert.True(t, body.closed)
+
+	status = http.StatusBadGateway
+	require.NoError(t, httpx.New("http://example.com").RoundTripper(transport).DoAndClose())
+	assert.True(t, body.closed)
+
+	err := httpx.New("http://example.com").RoundTripper(transport).ExpectSuccess().DoAndClose()
+	var httpErr *httpx.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+	assert.Equal(t, "ignored", string(httpErr.Body))
+	assert.True(t, body.closed)
+}
+
+func TestRequestBuilder_DoJSONArray(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("["))
+		for i := 0; i < 1000; i++ {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			fmt.Fprintf(w, `{"id":%d}`, i)
+		}
+		w.Write([]byte("]"))
+	}))
+	defer server.Close()
+
+	var ids []int
+	err := httpx.New(server.URL).DoJSONArray(func(decode func(v interface{}) error) error {
+		var item struct{ ID int }
+		if err := decode(&item); err != nil {
+			return err
+		}
+		ids = append(ids, item.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 1000)
+	for i, id := range ids {
+		assert.Equal(t, i, id)
+	}
+
+	// Elements the callback does not decode are skipped.
+	var count int
+	err = httpx.New(server.URL).DoJSONArray(func(decode func(v interface{}) error) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1000, count)
+
+	stop := errors.New("stop")
+	err = httpx.New(server.URL).DoJSONArray(func(decode func(v interface{}) error) error { return stop })
+	assert.ErrorIs(t, err, stop)
+}
+
+func TestRequestBuilder_DoJSONArray_NotArray(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"items":[]}`))
+	}))
+	defer server.Close()
+
+	err := httpx.New(server.URL).DoJSONArray(func(decode func(v interface{}) error) error { return nil })
+	assert.ErrorContains(t, err, "expected a JSON array")
+}
+
+func TestRequestBuilder_DoStreaming(t *testing.T) {
+	disconnected := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(disconnected)
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+				fmt.Fprint(w, "data: tick\n\n")
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	resp, cancel, err := httpx.New(server.URL).DoStreaming()
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	buf := make([]byte, len("data: tick\n\n"))
+	_, err = io.ReadFull(resp.Body, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "data: tick\n\n", string(buf))
+
+	cancel()
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("server did not see the disconnect")
+	}
+	_, err = io.ReadAll(resp.Body)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRequestBuilder_DoResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Version", "2")
+		w.Write([]byte(`{"id":42}`))
+	}))
+	defer server.Close()
+
+	var v struct{ ID int }
+	err := httpx.New(server.URL).DoResponse().
+		AssertStatus(http.StatusOK, http.StatusCreated).
+		AssertHeader("Content-Type", "application/json").
+		AssertHeader("X-Version", "2").
+		JSON(&v)
+	require.NoError(t, err)
+	assert.Equal(t, 42, v.ID)
+
+	// The first failed assertion wins and the rest of the chain is skipped.
+	v.ID = 0
+	err = httpx.New(server.URL).DoResponse().
+		AssertStatus(http.StatusCreated).
+		AssertHeader("X-Version", "3").
+		JSON(&v)
+	assert.ErrorIs(t, err, httpx.ErrAssertionFailed)
+	assert.Contains(t, err.Error(), "status 200")
+	assert.Zero(t, v.ID)
+
+	resp := httpx.New(server.URL).DoResponse().AssertHeader("X-Version", "3")
+	assert.ErrorIs(t, resp.Err(), httpx.ErrAssertionFailed)
+	assert.Contains(t, resp.Err().Error(), `header X-Version is "2"`)
+
+	err = httpx.New("").DoResponse().AssertStatus(http.StatusOK).JSON(&v)
+	assert.ErrorIs(t, err, httpx.ErrEmptyURL)
+}
+
+func TestRequestBuilder_DoJSONOrError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`{"id":1}`))
+		case "/invalid":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"code":"invalid","message":"name is required"}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>bad gateway</html>"))
+		}
+	}))
+	defer server.Close()
+
+	type success struct{ ID int }
+	type failure struct{ Code, Message string }
+
+	var s success
+	var f failure
+	ok, err := httpx.New(server.URL+"/ok").DoJSONOrError(&s, &f)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, s.ID)
+
+	ok, err = httpx.New(server.URL+"/invalid").DoJSONOrError(&s, &f)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, failure{Code: "invalid", Message: "name is required"}, f)
+
+	ok, err = httpx.New(server.URL+"/gateway").DoJSONOrError(&s, &f)
+	assert.False(t, ok)
+	var httpErr *httpx.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+	assert.Equal(t, "<html>bad gateway</html>", string(httpErr.Body))
+}
+
+func TestCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc123", HttpOnly: true})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark"})
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).Do()
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	cookies := httpx.Cookies(resp)
+	require.Len(t, cookies, 2)
+	assert.True(t, cookies[0].HttpOnly)
+
+	value, ok := httpx.CookieValue(resp, "theme")
+	assert.True(t, ok)
+	assert.Equal(t, "dark", value)
+
+	_, ok = httpx.CookieValue(resp, "missing")
+	assert.False(t, ok)
+}
+
+func TestRequestBuilder_Decode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"name":"widget"}`))
+		case "/xml":
+			w.Header().Set("Content-Type", "text/xml")
+			w.Write([]byte(`<item><name>gadget</name></item>`))
+		case "/error":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"not found"}`))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("name=other"))
+		}
+	}))
+	defer server.Close()
+
+	type item struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	var v item
+	require.NoError(t, httpx.New(server.URL+"/json").Decode(&v))
+	assert.Equal(t, "widget", v.Name)
+
+	require.NoError(t, httpx.New(server.URL+"/xml").Decode(&v))
+	assert.Equal(t, "gadget", v.Name)
+
+	var httpErr *httpx.HTTPError
+	assert.ErrorAs(t, httpx.New(server.URL+"/error").Decode(&v), &httpErr)
+
+	err := httpx.New(server.URL + "/text").Decode(&v)
+	assert.ErrorIs(t, err, httpx.ErrUnsupportedContentType)
+}
+
+func TestRequestBuilder_StatusErrorMapper(t *testing.T) {
+	errNotFound := errors.New("not found")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer server.Close()
+
+	mapper := func(code int) error {
+		if code == http.StatusNotFound {
+			return errNotFound
+		}
+		return nil
+	}
+
+	var v struct{}
+	err := httpx.New(server.URL + "/missing").StatusErrorMapper(mapper).DoJSON(&v)
+	assert.ErrorIs(t, err, errNotFound)
+	var httpErr *httpx.HTTPError
+	require.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+
+	err = httpx.New(server.URL + "/missing").StatusErrorMapper(mapper).ExpectSuccess().DoAndClose()
+	assert.ErrorIs(t, err, errNotFound)
+
+	err = httpx.New(server.URL + "/missing").StatusErrorMapper(mapper).DoResponse().AssertStatus(http.StatusOK).Err()
+	assert.ErrorIs(t, err, errNotFound)
+	assert.ErrorIs(t, err, httpx.ErrAssertionFailed)
+
+	err = httpx.New(server.URL + "/conflict").StatusErrorMapper(mapper).DoJSON(&v)
+	assert.NotErrorIs(t, err, errNotFound)
+	require.ErrorAs(t, err, &httpErr)
+	assert.Nil(t, httpErr.Err)
+}
+
+func TestRequestBuilder_UseNumber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":9007199254740993}`))
+	}))
+	defer server.Close()
+
+	var lossy map[string]interface{}
+	require.NoError(t, httpx.New(server.URL).DoJSON(&lossy))
+	assert.Equal(t, float64(9007199254740992), lossy["id"])
+
+	var exact map[string]interface{}
+	require.NoError(t, httpx.New(server.URL).UseNumber().DoJSON(&exact))
+	assert.Equal(t, json.Number("9007199254740993"), exact["id"])
+}
+
+func TestResponse_FinalURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/short", http.RedirectHandler("/hop", http.StatusMovedPermanently))
+	mux.Handle("/hop", http.RedirectHandler("/article?id=7", http.StatusFound))
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL + "/short").Execute()
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, server.URL+"/article?id=7", resp.FinalURL().String())
+}
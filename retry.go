@@ -0,0 +1,256 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Backoff computes how long to wait before a retry.
+type Backoff interface {
+	// NextDelay returns the delay before the given retry,
+	// where attempt is 1 for the first retry.
+	NextDelay(attempt int) time.Duration
+}
+
+// WithBackoff sets the strategy used to wait between retries.
+// Without a backoff, retries are sent immediately.
+func (r *RequestBuilder) WithBackoff(b Backoff) *RequestBuilder {
+	r.backoff = b
+	return r
+}
+
+// RetryPolicy reports whether an attempt should be retried.
+// resp is nil when err is not.
+type RetryPolicy func(resp *http.Response, err error) bool
+
+// RetryIf sets the policy deciding which attempts are retried.
+// By default only transport errors are retried. Errors classified by
+// IsRetryableError are retried even if policy rejects them.
+func (r *RequestBuilder) RetryIf(policy RetryPolicy) *RequestBuilder {
+	r.retryIf = policy
+	return r
+}
+
+// RetryOnStatus enables retrying responses with a retryable status code,
+// in addition to transport errors. See StatusRetryPolicy.
+func (r *RequestBuilder) RetryOnStatus() *RequestBuilder {
+	return r.RetryIf(StatusRetryPolicy)
+}
+
+// StatusRetryPolicy retries transport errors, 5xx responses and the
+// 408 Request Timeout and 429 Too Many Requests client errors.
+// Other 4xx responses are deterministic failures and are returned immediately.
+func StatusRetryPolicy(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch code := resp.StatusCode; {
+	case code == http.StatusRequestTimeout, code == http.StatusTooManyRequests:
+		return true
+	default:
+		return code >= 500
+	}
+}
+
+// IsRetryableError reports whether err is a transient transport failure
+// that is worth retrying regardless of the retry policy, such as a
+// connection reset by a restarting server or an HTTP/2 GOAWAY sent by a
+// server shutting down, after which a new connection succeeds.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	// Some errors only carry the reset as text, e.g. when a proxy or a
+	// wrapping library flattened the original error. The HTTP/2 GOAWAY
+	// errors of net/http are unexported and can only be matched as text.
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset by peer") || strings.Contains(msg, "GOAWAY")
+}
+
+func (r *RequestBuilder) shouldRetry(resp *http.Response, err error) bool {
+	if r.retryIf != nil {
+		return r.retryIf(resp, err) || IsRetryableError(err)
+	}
+	return err != nil
+}
+
+// OnRetry registers fn to be called before each retry, but not before the
+// first attempt. attempt is 1 for the first retry, and resp and err hold
+// the outcome that triggered it. The response body is closed after fn returns.
+func (r *RequestBuilder) OnRetry(fn func(attempt int, resp *http.Response, err error)) *RequestBuilder {
+	r.onRetry = fn
+	return r
+}
+
+// BeforeSend registers fn to run once on the built request right before
+// the first attempt, after every Validate hook, for final steps such as
+// signing. Retries reuse its result rather than calling fn again; use
+// OnRetry for per-attempt logic. If fn returns an error, nothing is sent.
+// A request re-issued by RetryOnBodyError is a new send, so fn runs again
+// for it.
+func (r *RequestBuilder) BeforeSend(fn func(req *http.Request) error) *RequestBuilder {
+	r.beforeSend = append(r.beforeSend, fn)
+	return r
+}
+
+// RetryBudget is a token bucket limiting how many retries are sent, so a
+// flood of failing requests is not amplified by retries. Each retry consumes
+// one token. Share one budget between builders; it is safe for concurrent use.
+type RetryBudget struct {
+	mu       sync.Mutex
+	capacity float64
+	tokens   float64
+	rate     float64
+	last     time.Time
+}
+
+// NewRetryBudget creates a full budget holding up to capacity tokens
+// and refilled at refillPerSecond tokens per second.
+func NewRetryBudget(capacity int, refillPerSecond float64) *RetryBudget {
+	return &RetryBudget{
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		rate:     refillPerSecond,
+		last:     time.Now(),
+	}
+}
+
+// Allow consumes a token and reports whether one was available.
+func (b *RetryBudget) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Available returns the number of whole tokens left.
+func (b *RetryBudget) Available() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill()
+	return int(b.tokens)
+}
+
+func (b *RetryBudget) refill() {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
+
+// WithRetryBudget makes retries consume tokens from budget.
+// When the budget is empty, the last attempt is returned without retrying.
+func (r *RequestBuilder) WithRetryBudget(budget *RetryBudget) *RequestBuilder {
+	r.retryBudget = budget
+	return r
+}
+
+// SleepFunc replaces the function used to wait between retries, which by
+// default waits for d or until ctx is done. A stub that records d and
+// returns at once lets tests check backoff timing without real waits.
+func (r *RequestBuilder) SleepFunc(fn func(ctx context.Context, d time.Duration) error) *RequestBuilder {
+	r.sleep = fn
+	return r
+}
+
+func (r *RequestBuilder) sleepFunc() func(ctx context.Context, d time.Duration) error {
+	if r.sleep != nil {
+		return r.sleep
+	}
+	return sleepContext
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ConstantBackoff waits the same delay before every retry.
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+// NextDelay implements Backoff.
+func (b ConstantBackoff) NextDelay(int) time.Duration {
+	return b.Delay
+}
+
+// ExponentialBackoff doubles the delay after every retry, starting at Base.
+// If Max is positive, the delay never exceeds it; otherwise it stops growing
+// before it would overflow time.Duration.
+type ExponentialBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+	// Jitter, between 0 and 1, randomly shortens each delay by up to that
+	// fraction, so clients failing together do not retry in lockstep.
+	Jitter float64
+}
+
+// NextDelay implements Backoff.
+func (b ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	delay := b.Base
+	for i := 1; i < attempt; i++ {
+		if (b.Max > 0 && delay >= b.Max) || delay > math.MaxInt64/2 {
+			break
+		}
+		delay *= 2
+	}
+	delay = capDelay(delay, b.Max)
+	if b.Jitter > 0 {
+		delay -= time.Duration(b.Jitter * randomFloat64() * float64(delay))
+	}
+	return delay
+}
+
+// FibonacciBackoff grows the delay along the Fibonacci sequence
+// (Base, Base, 2*Base, 3*Base, 5*Base, ...).
+// If Max is positive, the delay never exceeds it; otherwise it stops growing
+// before it would overflow time.Duration.
+type FibonacciBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// NextDelay implements Backoff.
+func (b FibonacciBackoff) NextDelay(attempt int) time.Duration {
+	prev, delay := time.Duration(0), b.Base
+	for i := 1; i < attempt; i++ {
+		if (b.Max > 0 && delay >= b.Max) || delay > math.MaxInt64-prev {
+			break
+		}
+		prev, delay = delay, prev+delay
+	}
+	return capDelay(delay, b.Max)
+}
+
+func capDelay(delay, max time.Duration) time.Duration {
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
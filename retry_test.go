@@ -0,0 +1,401 @@
+package httpx_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func delays(b httpx.Backoff, n int) []time.Duration {
+	var result []time.Duration
+	for attempt := 1; attempt <= n; attempt++ {
+		result = append(result, b.NextDelay(attempt))
+	}
+	return result
+}
+
+func TestConstantBackoff(t *testing.T) {
+	b := httpx.ConstantBackoff{Delay: time.Second}
+	assert.Equal(t, []time.Duration{time.Second, time.Second, time.Second}, delays(b, 3))
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	b := httpx.ExponentialBackoff{Base: 100 * time.Millisecond, Max: time.Second}
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}, delays(b, 6))
+}
+
+func TestFibonacciBackoff(t *testing.T) {
+	b := httpx.FibonacciBackoff{Base: 10 * time.Millisecond, Max: 100 * time.Millisecond}
+	assert.Equal(t, []time.Duration{
+		10 * time.Millisecond,
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		30 * time.Millisecond,
+		50 * time.Millisecond,
+		80 * time.Millisecond,
+		100 * time.Millisecond,
+	}, delays(b, 7))
+}
+
+func TestBackoff_NoOverflow(t *testing.T) {
+	backoffs := []httpx.Backoff{
+		httpx.ExponentialBackoff{Base: time.Second},
+		httpx.FibonacciBackoff{Base: time.Second},
+	}
+	for _, b := range backoffs {
+		prev := b.NextDelay(1)
+		for _, attempt := range []int{35, 64, 100, 1000} {
+			delay := b.NextDelay(attempt)
+			assert.Greater(t, delay, time.Duration(0), "%T attempt %d", b, attempt)
+			assert.GreaterOrEqual(t, delay, prev, "%T attempt %d", b, attempt)
+			prev = delay
+		}
+	}
+}
+
+func TestRequestBuilder_WithBackoff(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if !assert.NoError(t, err) {
+				return
+			}
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	resp, err := httpx.New(server.URL).
+		Retry(3).
+		WithBackoff(httpx.ConstantBackoff{Delay: 20 * time.Millisecond}).
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, attempts)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestRequestBuilder_RetryOnStatus(t *testing.T) {
+	tests := []struct {
+		status   int
+		attempts int
+	}{
+		{http.StatusBadRequest, 1},
+		{http.StatusNotFound, 1},
+		{http.StatusRequestTimeout, 3},
+		{http.StatusTooManyRequests, 3},
+		{http.StatusServiceUnavailable, 3},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			var attempts int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			resp, err := httpx.New(server.URL).Retry(3).RetryOnStatus().Do()
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, resp.StatusCode)
+			assert.Equal(t, tt.attempts, attempts)
+		})
+	}
+}
+
+func TestRequestBuilder_RetryIf(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 2 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).Retry(3).RetryIf(func(resp *http.Response, err error) bool {
+		return err != nil || resp.StatusCode == http.StatusConflict
+	}).Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestRequestBuilder_WithRetryBudget(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	budget := httpx.NewRetryBudget(3, 0)
+
+	// The first request retries twice, leaving one token.
+	resp, err := httpx.New(server.URL).Retry(3).RetryOnStatus().WithRetryBudget(budget).Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 1, budget.Available())
+
+	// The second request can only retry once before the budget runs dry.
+	attempts = 0
+	_, err = httpx.New(server.URL).Retry(3).RetryOnStatus().WithRetryBudget(budget).Do()
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, 0, budget.Available())
+
+	// With an empty budget no retries happen at all.
+	attempts = 0
+	_, err = httpx.New(server.URL).Retry(3).RetryOnStatus().WithRetryBudget(budget).Do()
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestRequestBuilder_OnRetry(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts <= 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var retries []int
+	resp, err := httpx.New(server.URL).
+		Retry(5).
+		RetryOnStatus().
+		OnRetry(func(attempt int, resp *http.Response, err error) {
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+			retries = append(retries, attempt)
+		}).
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []int{1, 2, 3}, retries)
+}
+
+func TestIsRetryableError(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	assert.True(t, httpx.IsRetryableError(reset))
+	assert.True(t, httpx.IsRetryableError(errors.New("proxy: connection reset by peer")))
+	assert.False(t, httpx.IsRetryableError(errors.New("x509: certificate has expired")))
+	assert.False(t, httpx.IsRetryableError(nil))
+}
+
+func TestRequestBuilder_RetryOnConnectionReset(t *testing.T) {
+	var attempts int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	// The policy rejects every error, yet the reset is still retried.
+	resp, err := httpx.New("http://example.com").
+		RoundTripper(transport).
+		Retry(2).
+		RetryIf(func(resp *http.Response, err error) bool { return false }).
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestRequestBuilder_SleepFunc(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var slept []time.Duration
+	start := time.Now()
+	resp, err := httpx.New(server.URL).
+		Retry(4).
+		RetryOnStatus().
+		WithBackoff(httpx.ExponentialBackoff{Base: time.Second, Max: 3 * time.Second}).
+		SleepFunc(func(ctx context.Context, d time.Duration) error {
+			slept = append(slept, d)
+			return nil
+		}).
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, slept)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRequestBuilder_NoRetry(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	builders := map[string]*httpx.RequestBuilder{
+		"default":  httpx.New(server.URL),
+		"Retry(0)": httpx.New(server.URL).Retry(0),
+		"Retry(1)": httpx.New(server.URL).Retry(1),
+		"NoRetry":  httpx.New(server.URL).Retry(5).NoRetry(),
+	}
+	for name, builder := range builders {
+		t.Run(name, func(t *testing.T) {
+			attempts = 0
+			resp, err := builder.RetryOnStatus().WithBackoff(httpx.ConstantBackoff{Delay: time.Millisecond}).Do()
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, 1, attempts)
+		})
+	}
+}
+
+func TestRequestBuilder_RetryOnGoAway(t *testing.T) {
+	var attempts int
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New(`http2: server sent GOAWAY and closed the connection; LastStreamID=1, ErrCode=NO_ERROR, debug=""`)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	resp, err := httpx.New("http://example.com").
+		RoundTripper(transport).
+		Retry(2).
+		RetryIf(func(resp *http.Response, err error) bool { return false }).
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestRequestBuilder_BeforeSend(t *testing.T) {
+	var signatures []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatures = append(signatures, r.Header.Get("X-Signature"))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var calls int
+	resp, err := httpx.New(server.URL).
+		Retry(3).
+		RetryOnStatus().
+		BeforeSend(func(req *http.Request) error {
+			calls++
+			req.Header.Set("X-Signature", fmt.Sprintf("sig-%d", calls))
+			return nil
+		}).
+		Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []string{"sig-1", "sig-1", "sig-1"}, signatures)
+
+	errSign := errors.New("no key")
+	_, err = httpx.New(server.URL).BeforeSend(func(*http.Request) error { return errSign }).Do()
+	assert.ErrorIs(t, err, errSign)
+	assert.Len(t, signatures, 3)
+}
+
+func TestRequestBuilder_NoBodyReplay(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).Post().Json(map[string]int{"amount": 100}).
+		Retry(3).RetryOnStatus().NoBodyReplay().Do()
+	assert.ErrorIs(t, err, httpx.ErrBodyNotReplayable)
+	assert.Nil(t, resp)
+	assert.Equal(t, 1, attempts)
+
+	// Requests without a body are still retried.
+	attempts = 0
+	resp, err = httpx.New(server.URL).Retry(3).RetryOnStatus().NoBodyReplay().Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRequestBuilder_NoBodyReplay_SignHMAC(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		assert.NotEmpty(t, r.Header.Get("X-Signature"))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	builder := httpx.New(server.URL).Post().Json(map[string]int{"amount": 100}).
+		SignHMAC([]byte("secret"), nil, sha256.New).
+		Retry(3).RetryOnStatus().NoBodyReplay()
+	resp, err := builder.Do()
+	assert.ErrorIs(t, err, httpx.ErrBodyNotReplayable)
+	assert.Nil(t, resp)
+	assert.Equal(t, 1, attempts)
+
+	// The builder itself can still read its body.
+	body, err := builder.BodyBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"amount":100}`, string(body))
+}
+
+func TestRequestBuilder_RetryStopsWhenContextDone(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	budget := httpx.NewRetryBudget(10, 0)
+	var retries int
+	_, err := httpx.New(server.URL).
+		Retry(5).
+		WithRetryBudget(budget).
+		OnRetry(func(int, *http.Response, error) { retries++ }).
+		DoWithContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, attempts)
+	assert.Zero(t, retries)
+	assert.Equal(t, 10, budget.Available())
+}
@@ -0,0 +1,51 @@
+package httpx_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func hmacHex(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestRequestBuilder_SignHMAC(t *testing.T) {
+	req, err := httpx.New("http://example.com/orders?id=1").
+		Post().
+		SignHMAC([]byte("secret"), []string{"X-Date", "Content-Type"}, sha256.New).
+		SetHeader("X-Date", "Mon, 02 Jan 2006 15:04:05 GMT").
+		Json(map[string]int{"amount": 10}).
+		Build()
+	require.NoError(t, err)
+
+	// HMAC-SHA256("secret", "POST\n/orders?id=1\nx-date:Mon, 02 Jan 2006 15:04:05 GMT\ncontent-type:application/json\n{\"amount\":10}")
+	assert.Equal(t, "f9a5b73b5c0c861892cd810a663c1bf92f4da036467f103311027a0cd9ea07c0", req.Header.Get("X-Signature"))
+}
+
+func TestRequestBuilder_Sign(t *testing.T) {
+	req, err := httpx.New("http://example.com/orders").
+		SetHeader("X-Key", "k1").
+		Sign(&httpx.HMACSigner{
+			Secret:          []byte("secret"),
+			Hash:            sha256.New,
+			Headers:         []string{"X-Key"},
+			SignatureHeader: "Authorization",
+			Canonicalize: func(req *http.Request, headers []string, body []byte) string {
+				return strings.Join([]string{req.Method, req.URL.Path, req.Header.Get(headers[0])}, "|")
+			},
+		}).
+		Build()
+	require.NoError(t, err)
+	assert.Equal(t, hmacHex("secret", "GET|/orders|k1"), req.Header.Get("Authorization"))
+}
@@ -0,0 +1,114 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Stats describes a completed call to Do.
+type Stats struct {
+	Method string
+	URL    string
+	// Attempts is the number of attempts made, including retries.
+	Attempts int
+	// Duration is the total time spent, including retries and backoff.
+	Duration time.Duration
+	// StatusCode is the status of the final response, or 0 if there was none.
+	StatusCode int
+	// TimeToFirstByte is the time from sending the final attempt to
+	// receiving the first byte of its response. It is only measured by
+	// DoFirstByte and is 0 otherwise.
+	TimeToFirstByte time.Duration
+	// Err is the error returned by Do.
+	Err error
+	// Metadata holds the labels attached with WithMetadata.
+	Metadata map[string]string
+}
+
+// OnComplete registers fn to be called with the stats of every call to Do,
+// whether it succeeded or not. It is useful for logging and metrics.
+func (r *RequestBuilder) OnComplete(fn func(Stats)) *RequestBuilder {
+	r.onComplete = fn
+	return r
+}
+
+// WithMetadata attaches a label, such as a logical request name, to the
+// request. Labels are reported in Stats and stored on the request context,
+// where transports can read them with MetadataFromContext.
+func (r *RequestBuilder) WithMetadata(key, value string) *RequestBuilder {
+	metadata := make(map[string]string, len(r.metadata)+1)
+	for k, v := range r.metadata {
+		metadata[k] = v
+	}
+	metadata[key] = value
+	r.metadata = metadata
+	return r
+}
+
+// DoFirstByte sends the request like DoWithContext, which returns as soon
+// as the response headers arrive, and also returns the stats of the call
+// with TimeToFirstByte measured, as a latency metric excluding the time
+// spent transferring the body. The stats are passed to OnComplete too.
+func (r *RequestBuilder) DoFirstByte(ctx context.Context) (*http.Response, Stats, error) {
+	return r.doStats(ctx, &firstByteTrace{})
+}
+
+// firstByteTrace measures the time to first byte of the last attempt.
+type firstByteTrace struct {
+	mu        sync.Mutex
+	start     time.Time
+	firstByte time.Time
+}
+
+func (t *firstByteTrace) withContext(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.start, t.firstByte = time.Now(), time.Time{}
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.firstByte = time.Now()
+		},
+	})
+}
+
+func (t *firstByteTrace) timeToFirstByte() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.firstByte.IsZero() {
+		return 0
+	}
+	return t.firstByte.Sub(t.start)
+}
+
+type metadataKey struct{}
+
+// MetadataFromContext returns the labels attached to a request with
+// WithMetadata, or nil if there are none. The map must not be modified.
+func MetadataFromContext(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return metadata
+}
+
+func (r *RequestBuilder) stats(resp *http.Response, attempts int, duration time.Duration, err error) Stats {
+	stats := Stats{
+		Attempts: attempts,
+		Duration: duration,
+		Err:      err,
+		Metadata: r.metadata,
+	}
+	if r.req != nil {
+		stats.Method = r.req.Method
+		stats.URL = r.req.URL.String()
+	}
+	if resp != nil {
+		stats.StatusCode = resp.StatusCode
+	}
+	return stats
+}
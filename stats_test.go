@@ -0,0 +1,80 @@
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestRequestBuilder_OnComplete(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	var stats httpx.Stats
+	var fromContext map[string]string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		fromContext = httpx.MetadataFromContext(req.Context())
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	resp, err := httpx.New(server.URL).
+		Post().
+		Retry(3).
+		RetryOnStatus().
+		RoundTripper(transport).
+		WithMetadata("name", "create-user").
+		WithMetadata("team", "identity").
+		OnComplete(func(s httpx.Stats) { stats = s }).
+		Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	assert.Equal(t, http.MethodPost, stats.Method)
+	assert.Equal(t, server.URL, stats.URL)
+	assert.Equal(t, 2, stats.Attempts)
+	assert.Equal(t, http.StatusCreated, stats.StatusCode)
+	assert.Positive(t, stats.Duration)
+	assert.NoError(t, stats.Err)
+	assert.Equal(t, map[string]string{"name": "create-user", "team": "identity"}, stats.Metadata)
+	assert.Equal(t, stats.Metadata, fromContext)
+}
+
+func TestRequestBuilder_DoFirstByte(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var completed httpx.Stats
+	resp, stats, err := httpx.New(server.URL).
+		OnComplete(func(s httpx.Stats) { completed = s }).
+		DoFirstByte(context.Background())
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.GreaterOrEqual(t, stats.TimeToFirstByte, 20*time.Millisecond)
+	assert.LessOrEqual(t, stats.TimeToFirstByte, stats.Duration)
+	assert.Equal(t, stats.TimeToFirstByte, completed.TimeToFirstByte)
+
+	// Plain Do does not measure it.
+	resp, err = httpx.New(server.URL).OnComplete(func(s httpx.Stats) { completed = s }).Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Zero(t, completed.TimeToFirstByte)
+}
@@ -0,0 +1,111 @@
+package httpx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Tracker counts the in-flight requests sent through the clients it wraps,
+// so a program shutting down can wait for them to finish. A request is in
+// flight from the moment it is sent until its response body is closed.
+// The zero value is not usable; create one with NewTracker.
+type Tracker struct {
+	mu       sync.Mutex
+	inFlight int
+	// idle is closed whenever no request is in flight.
+	idle chan struct{}
+}
+
+// NewTracker returns a Tracker with no requests in flight.
+func NewTracker() *Tracker {
+	idle := make(chan struct{})
+	close(idle)
+	return &Tracker{idle: idle}
+}
+
+// Client returns a copy of client whose requests are tracked. A nil client
+// stands for http.DefaultClient. Set the result on builders with Client.
+// Transport helpers such as DisableHTTP2 cannot configure the tracked
+// client's transport, so configure client's transport before wrapping it.
+func (t *Tracker) Client(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	tracked := *client
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	tracked.Transport = &trackingTransport{tracker: t, next: next}
+	return &tracked
+}
+
+// InFlight returns the number of requests in flight.
+func (t *Tracker) InFlight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.inFlight
+}
+
+// Wait blocks until no request is in flight or ctx is done, returning
+// ctx.Err() in the latter case.
+func (t *Tracker) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	idle := t.idle
+	t.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Tracker) start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.inFlight == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.inFlight++
+}
+
+func (t *Tracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inFlight--
+	if t.inFlight == 0 {
+		close(t.idle)
+	}
+}
+
+type trackingTransport struct {
+	tracker *Tracker
+	next    http.RoundTripper
+}
+
+func (t *trackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.tracker.start()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.tracker.done()
+		return nil, err
+	}
+	resp.Body = &trackedBody{ReadCloser: resp.Body, done: t.tracker.done}
+	return resp, nil
+}
+
+// trackedBody marks its request done when closed.
+type trackedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *trackedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
@@ -0,0 +1,52 @@
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestTracker(t *testing.T) {
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Done()
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tracker := httpx.NewTracker()
+	client := tracker.Client(nil)
+	require.NoError(t, tracker.Wait(context.Background()))
+
+	started.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			resp, err := httpx.New(server.URL).Client(client).Do()
+			if assert.NoError(t, err) {
+				resp.Body.Close()
+			}
+		}()
+	}
+	started.Wait()
+	assert.Equal(t, 3, tracker.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, tracker.Wait(ctx), context.DeadlineExceeded)
+
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, tracker.Wait(ctx))
+	assert.Equal(t, 0, tracker.InFlight())
+}
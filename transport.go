@@ -0,0 +1,368 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TransportOption configures the *http.Transport used to send a request.
+type TransportOption func(transport *http.Transport)
+
+// Transport applies opts to the transport used to send the request.
+// The transport of the configured client is cloned the first time the
+// builder changes it, and every later change, whether from Transport or
+// from helpers like ProxyFromEnvironment, is made to that same clone.
+func (r *RequestBuilder) Transport(opts ...TransportOption) *RequestBuilder {
+	return r.withTransport(func(transport *http.Transport) {
+		for _, opt := range opts {
+			opt(transport)
+		}
+	})
+}
+
+// ProxyFromEnvironment makes the request honor the HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY environment variables, even when the configured client uses
+// a transport that does not.
+func (r *RequestBuilder) ProxyFromEnvironment() *RequestBuilder {
+	return r.withTransport(func(transport *http.Transport) {
+		transport.Proxy = http.ProxyFromEnvironment
+	})
+}
+
+// DisableHTTP2 restricts the request to HTTP/1.1, working around servers
+// with broken HTTP/2 support.
+func (r *RequestBuilder) DisableHTTP2() *RequestBuilder {
+	return r.withTransport(func(transport *http.Transport) {
+		transport.ForceAttemptHTTP2 = false
+		// A non-nil, empty map disables the automatic HTTP/2 upgrade.
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		if transport.TLSClientConfig != nil {
+			config := transport.TLSClientConfig.Clone()
+			protos := config.NextProtos[:0:0]
+			for _, proto := range config.NextProtos {
+				if proto != "h2" {
+					protos = append(protos, proto)
+				}
+			}
+			config.NextProtos = protos
+			transport.TLSClientConfig = config
+		}
+	})
+}
+
+// UnixSocket sends the request over the Unix domain socket at path instead
+// of TCP, as used by the Docker API and other local daemons. The host in
+// the request URL is only a placeholder, e.g. "http://localhost/version".
+//
+// Dialer settings such as DialTimeout and ReadTimeout apply to the socket
+// connection whether they are set before or after UnixSocket.
+func (r *RequestBuilder) UnixSocket(path string) *RequestBuilder {
+	return r.withTransport(func(transport *http.Transport) {
+		r.unixSocket = path
+		transport.Proxy = nil
+		r.chainDial(transport)
+	})
+}
+
+// Client sets the client used to send the request. The client is never
+// modified: later transport or redirect tweaks are applied to copies, so
+// it can safely be shared between builders.
+func (r *RequestBuilder) Client(client *http.Client) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	r.client = client
+	return r
+}
+
+// DialTimeout limits how long establishing a connection may take, so an
+// unreachable host fails fast while a slow response body is still allowed
+// to take its time. TLS handshakes are not included.
+func (r *RequestBuilder) DialTimeout(d time.Duration) *RequestBuilder {
+	return r.withDial(func(dial dialFunc) dialFunc {
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+			return dial(ctx, network, addr)
+		}
+	})
+}
+
+// KeepAlive sets how long a connection stays idle before TCP keep-alive
+// probes are sent, so a dead peer on a long-lived connection, such as a
+// stream, is detected. A negative d disables keep-alive probes.
+func (r *RequestBuilder) KeepAlive(d time.Duration) *RequestBuilder {
+	return r.withDial(func(dial dialFunc) dialFunc {
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			tcp := conn
+			if dc, ok := conn.(*deadlineConn); ok {
+				tcp = dc.Conn
+			}
+			if tcp, ok := tcp.(*net.TCPConn); ok {
+				if err = setKeepAlive(tcp, d); err != nil {
+					conn.Close()
+					return nil, err
+				}
+			}
+			return conn, nil
+		}
+	})
+}
+
+func setKeepAlive(conn *net.TCPConn, d time.Duration) error {
+	if d < 0 {
+		return conn.SetKeepAlive(false)
+	}
+	if err := conn.SetKeepAlive(true); err != nil {
+		return err
+	}
+	return conn.SetKeepAlivePeriod(d)
+}
+
+// dialFunc is the signature of http.Transport.DialContext.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// withDial replaces the transport's dialer with the one returned by wrap,
+// which receives the current dialer, ending in dialContext.
+func (r *RequestBuilder) withDial(wrap func(dial dialFunc) dialFunc) *RequestBuilder {
+	return r.withTransport(func(transport *http.Transport) {
+		r.chainDial(transport)
+		transport.DialContext = wrap(transport.DialContext)
+	})
+}
+
+// chainDial makes dialContext the innermost dialer of transport, once per
+// transport, keeping the transport's own dialer as the one it falls back to.
+func (r *RequestBuilder) chainDial(transport *http.Transport) {
+	if r.dialChained {
+		return
+	}
+	r.baseDial = transport.DialContext
+	if r.baseDial == nil {
+		r.baseDial = (&net.Dialer{}).DialContext
+	}
+	transport.DialContext = r.dialContext
+	r.dialChained = true
+}
+
+// dialContext connects to the socket set by UnixSocket, which may be set
+// after the dialer is wrapped, or with the transport's original dialer
+// otherwise.
+func (r *RequestBuilder) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if r.unixSocket != "" {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "unix", r.unixSocket)
+	}
+	return r.baseDial(ctx, network, addr)
+}
+
+// ReadTimeout fails a read from the connection that makes no progress for
+// d, such as a server stalling in the middle of a response body. Unlike a
+// client timeout it does not limit the total duration of a slow but
+// steady transfer. Idle pooled connections are closed after d as well.
+func (r *RequestBuilder) ReadTimeout(d time.Duration) *RequestBuilder {
+	return r.withConnDeadlines(func(conn *deadlineConn) { conn.readTimeout = d })
+}
+
+// WriteTimeout fails a write to the connection that makes no progress for
+// d, such as a request body upload to a server that stopped reading.
+func (r *RequestBuilder) WriteTimeout(d time.Duration) *RequestBuilder {
+	return r.withConnDeadlines(func(conn *deadlineConn) { conn.writeTimeout = d })
+}
+
+// withConnDeadlines wraps the transport's dialer so that new connections
+// are *deadlineConn configured by fn. Wrapping again reconfigures the
+// same connection instead of nesting another one.
+func (r *RequestBuilder) withConnDeadlines(fn func(conn *deadlineConn)) *RequestBuilder {
+	return r.withDial(func(dial dialFunc) dialFunc {
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			dc, ok := conn.(*deadlineConn)
+			if !ok {
+				dc = &deadlineConn{Conn: conn}
+			}
+			fn(dc)
+			return dc, nil
+		}
+	})
+}
+
+// deadlineConn extends its deadlines before every read and write.
+type deadlineConn struct {
+	net.Conn
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+}
+
+func (c *deadlineConn) Read(p []byte) (int, error) {
+	if c.readTimeout > 0 {
+		if err := c.Conn.SetReadDeadline(time.Now().Add(c.readTimeout)); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Read(p)
+}
+
+func (c *deadlineConn) Write(p []byte) (int, error) {
+	if c.writeTimeout > 0 {
+		if err := c.Conn.SetWriteDeadline(time.Now().Add(c.writeTimeout)); err != nil {
+			return 0, err
+		}
+	}
+	return c.Conn.Write(p)
+}
+
+// RoundTripper sets the transport used to send the request.
+// The configured client is copied, so other users of it are unaffected.
+// This is mainly useful for injecting a fake transport in tests.
+func (r *RequestBuilder) RoundTripper(rt http.RoundTripper) *RequestBuilder {
+	return r.withClient(func(client *http.Client) {
+		client.Transport = rt
+	})
+}
+
+var (
+	// ErrTooManyRedirects is returned when a request exceeds the limit set by MaxRedirects.
+	ErrTooManyRedirects = errors.New("httpx: too many redirects")
+	// ErrRedirectLoop is returned when a redirect leads back to a URL already visited.
+	ErrRedirectLoop = errors.New("httpx: redirect loop")
+)
+
+// MaxRedirects follows at most n redirects, failing with ErrTooManyRedirects
+// beyond that and with ErrRedirectLoop as soon as a redirect points back to
+// a URL already visited in the chain. A limit of 0 disables redirects:
+// the first 3xx response is returned as is, without an error.
+func (r *RequestBuilder) MaxRedirects(n int) *RequestBuilder {
+	return r.withClient(func(client *http.Client) {
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if n == 0 {
+				return http.ErrUseLastResponse
+			}
+			for _, prev := range via {
+				if prev.URL.String() == req.URL.String() {
+					return fmt.Errorf("%w: %s was already visited", ErrRedirectLoop, req.URL)
+				}
+			}
+			if len(via) > n {
+				return fmt.Errorf("%w: stopped after %d redirects", ErrTooManyRedirects, n)
+			}
+			return nil
+		}
+	})
+}
+
+var (
+	sharedClientOnce sync.Once
+	sharedClient     *http.Client
+	useSharedClient  atomic.Bool
+)
+
+// SharedClient returns a package-level client meant to be reused across
+// builders. Its transport keeps more idle connections per host than
+// http.DefaultTransport, so requests to many hosts, or many concurrent
+// requests to one host, reuse pooled connections instead of repeatedly
+// dialing and re-doing TLS handshakes. It has no overall timeout; bound
+// requests with a context instead.
+func SharedClient() *http.Client {
+	sharedClientOnce.Do(func() {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConns = 256
+		transport.MaxIdleConnsPerHost = 32
+		sharedClient = &http.Client{Transport: transport}
+	})
+	return sharedClient
+}
+
+// UseSharedClient makes builders without an explicitly configured client
+// send their requests with SharedClient instead of http.DefaultClient.
+func UseSharedClient(enabled bool) {
+	useSharedClient.Store(enabled)
+}
+
+// CloseIdleConnections closes the idle connections kept by SharedClient,
+// releasing their memory and file descriptors in long-running programs.
+// Connections in use are not interrupted.
+func CloseIdleConnections() {
+	SharedClient().CloseIdleConnections()
+}
+
+// CloseIdleConnections closes the idle connections kept by the client used
+// to send the request, including a transport cloned by the builder.
+func (r *RequestBuilder) CloseIdleConnections() {
+	r.httpClient().CloseIdleConnections()
+}
+
+// httpClient returns the client used to send the request.
+func (r *RequestBuilder) httpClient() *http.Client {
+	if r.client != nil {
+		return r.client
+	}
+	if useSharedClient.Load() {
+		return SharedClient()
+	}
+	return http.DefaultClient
+}
+
+// withClient applies fn to a copy of the client and uses the copy,
+// so the original client is never modified.
+func (r *RequestBuilder) withClient(fn func(client *http.Client)) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	client := *r.httpClient()
+	fn(&client)
+	r.client = &client
+	return r
+}
+
+// withTransport applies fn to the transport owned by the builder, cloning
+// the client's transport on first use and installing the clone on a copy
+// of the client, so the original client is never modified.
+func (r *RequestBuilder) withTransport(fn func(transport *http.Transport)) *RequestBuilder {
+	if r.err != nil {
+		return r
+	}
+	client := *r.httpClient()
+	if r.transport == nil || client.Transport != r.transport {
+		transport, err := cloneTransport(client.Transport)
+		if err != nil {
+			r.err = err
+			return r
+		}
+		r.transport = transport
+		// The new transport's dialer, if any, was not installed by the builder.
+		r.dialChained = false
+	}
+	fn(r.transport)
+	client.Transport = r.transport
+	r.client = &client
+	return r
+}
+
+// cloneTransport returns a deep copy of rt, including its TLS config, that
+// can be modified without affecting rt. A nil rt stands for the default
+// transport.
+func cloneTransport(rt http.RoundTripper) (*http.Transport, error) {
+	switch t := rt.(type) {
+	case nil:
+		return http.DefaultTransport.(*http.Transport).Clone(), nil
+	case *http.Transport:
+		return t.Clone(), nil
+	default:
+		return nil, fmt.Errorf("httpx: cannot configure transport of type %T", t)
+	}
+}
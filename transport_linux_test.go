@@ -0,0 +1,48 @@
+package httpx_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestRequestBuilder_KeepAlive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var conn net.Conn
+	capture := func(transport *http.Transport) {
+		dial := transport.DialContext
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			c, err := dial(ctx, network, addr)
+			conn = c
+			return c, err
+		}
+	}
+	resp, err := httpx.New(server.URL).KeepAlive(42 * time.Second).Transport(capture).Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	require.NoError(t, err)
+	var enabled, idle int
+	require.NoError(t, raw.Control(func(fd uintptr) {
+		enabled, err = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+		require.NoError(t, err)
+		idle, err = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE)
+		require.NoError(t, err)
+	}))
+	assert.Equal(t, 1, enabled)
+	assert.Equal(t, 42, idle)
+}
@@ -0,0 +1,405 @@
+package httpx_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eatmoreapple/httpx"
+)
+
+func TestRequestBuilder_ProxyFromEnvironment(t *testing.T) {
+	// net/http reads the proxy environment only once per process,
+	// so the assertions run in a fresh copy of the test binary.
+	if os.Getenv("HTTPX_TEST_PROXY") != "1" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestRequestBuilder_ProxyFromEnvironment$")
+		cmd.Env = append(os.Environ(), "HTTPX_TEST_PROXY=1")
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return
+	}
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "http://example.invalid/resource", r.URL.String())
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+	t.Setenv("HTTP_PROXY", proxy.URL)
+
+	builder := httpx.New("http://example.invalid/resource")
+	builder.ProxyFromEnvironment()
+
+	resp, err := builder.Do()
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRequestBuilder_RoundTripper(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "http://example.invalid/users", req.URL.String())
+		return &http.Response{
+			StatusCode: http.StatusTeapot,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader("canned")),
+			Request:    req,
+		}, nil
+	})
+
+	resp, err := httpx.New("http://example.invalid/users").RoundTripper(transport).Do()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "canned", string(body))
+}
+
+func TestRequestBuilder_MaxRedirects(t *testing.T) {
+	var hops int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hops++
+		http.Redirect(w, r, fmt.Sprintf("/hop/%d", hops), http.StatusFound)
+	}))
+	defer server.Close()
+
+	_, err := httpx.New(server.URL).MaxRedirects(3).Do()
+	assert.ErrorIs(t, err, httpx.ErrTooManyRedirects)
+	assert.Equal(t, 4, hops)
+}
+
+func TestRequestBuilder_MaxRedirects_Zero(t *testing.T) {
+	var hops int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hops++
+		http.Redirect(w, r, "/next", http.StatusFound)
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).MaxRedirects(0).Do()
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+	assert.Equal(t, "/next", resp.Header.Get("Location"))
+	assert.Equal(t, 1, hops)
+}
+
+func TestRequestBuilder_MaxRedirects_Loop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		default:
+			http.Redirect(w, r, "/a", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	_, err := httpx.New(server.URL + "/a").MaxRedirects(10).Do()
+	assert.ErrorIs(t, err, httpx.ErrRedirectLoop)
+}
+
+func TestRequestBuilder_DisableHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).RoundTripper(server.Client().Transport).Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+
+	resp, err = httpx.New(server.URL).RoundTripper(server.Client().Transport).DisableHTTP2().Do()
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+	assert.Equal(t, "HTTP/1.1", string(body))
+}
+
+func TestRequestBuilder_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "httpx.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello over " + r.URL.Path))
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	resp, err := httpx.New("http://localhost/unix").UnixSocket(path).Do()
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello over /unix", string(body))
+}
+
+func TestRequestBuilder_UnixSocket_KeepsDialerSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "httpx.sock")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10")
+		w.Write([]byte("12345"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	// ReadTimeout is set before UnixSocket and must still apply.
+	resp, err := httpx.New("http://localhost/unix").
+		ReadTimeout(100 * time.Millisecond).
+		UnixSocket(path).
+		Do()
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	start := time.Now()
+	_, err = io.ReadAll(resp.Body)
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRequestBuilder_Transport(t *testing.T) {
+	var first, second *http.Transport
+	builder := httpx.New("http://example.com").
+		ProxyFromEnvironment().
+		Transport(
+			func(transport *http.Transport) { transport.MaxIdleConnsPerHost = 7 },
+			func(transport *http.Transport) { first = transport },
+		).
+		DisableHTTP2().
+		Transport(func(transport *http.Transport) { second = transport })
+	_, err := builder.Build()
+	require.NoError(t, err)
+
+	// All tweaks land on a single clone, so earlier ones are kept.
+	assert.Same(t, first, second)
+	assert.NotNil(t, second.Proxy)
+	assert.Equal(t, 7, second.MaxIdleConnsPerHost)
+	assert.False(t, second.ForceAttemptHTTP2)
+	assert.NotSame(t, http.DefaultTransport, second)
+}
+
+func TestRequestBuilder_ClientTransportIsolation(t *testing.T) {
+	shared := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{}}}
+
+	var tweaked, untouched *http.Transport
+	_, err := httpx.New("https://example.com").
+		Client(shared).
+		Transport(func(transport *http.Transport) {
+			transport.TLSClientConfig.InsecureSkipVerify = true
+			tweaked = transport
+		}).
+		Build()
+	require.NoError(t, err)
+	_, err = httpx.New("https://example.com").
+		Client(shared).
+		Transport(func(transport *http.Transport) { untouched = transport }).
+		Build()
+	require.NoError(t, err)
+
+	assert.True(t, tweaked.TLSClientConfig.InsecureSkipVerify)
+	assert.False(t, untouched.TLSClientConfig.InsecureSkipVerify)
+	assert.False(t, shared.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+}
+
+func TestSharedClient(t *testing.T) {
+	closed := make(chan struct{}, 2)
+	newServer := func() *httptest.Server {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Host))
+		}))
+		server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateClosed {
+				closed <- struct{}{}
+			}
+		}
+		server.Start()
+		return server
+	}
+	first, second := newServer(), newServer()
+	defer first.Close()
+	defer second.Close()
+
+	httpx.UseSharedClient(true)
+	defer httpx.UseSharedClient(false)
+	assert.Same(t, httpx.SharedClient(), httpx.SharedClient())
+
+	for _, server := range []*httptest.Server{first, second, first, second} {
+		resp, err := httpx.New(server.URL).Do()
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, strings.TrimPrefix(server.URL, "http://"), string(body))
+	}
+
+	// Both hosts were served from the shared pool, so closing its idle
+	// connections closes exactly one connection per host.
+	httpx.SharedClient().CloseIdleConnections()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			t.Fatal("idle connection was not pooled by the shared client")
+		}
+	}
+	select {
+	case <-closed:
+		t.Fatal("requests opened more than one connection per host")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRequestBuilder_ReadTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "10")
+		w.Write([]byte("12345"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	resp, err := httpx.New(server.URL).
+		ReadTimeout(50 * time.Millisecond).
+		WriteTimeout(time.Second).
+		Do()
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	start := time.Now()
+	body, err := io.ReadAll(resp.Body)
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+	assert.Equal(t, "12345", string(body))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestRequestBuilder_CloseIdleConnections(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	builder := httpx.New(server.URL).DisableHTTP2()
+	resp, err := builder.Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	httpx.CloseIdleConnections()
+	builder.CloseIdleConnections()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+}
+
+func TestRequestBuilder_DialTimeout(t *testing.T) {
+	// The fake dialer hangs like a connection to a blackholed address.
+	hang := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	start := time.Now()
+	_, err := httpx.New("http://10.255.255.1").
+		Transport(func(transport *http.Transport) { transport.DialContext = hang }).
+		DialTimeout(50 * time.Millisecond).
+		Do()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The timeout covers connecting only, not waiting for the response.
+	resp, err := httpx.New(server.URL).DialTimeout(50 * time.Millisecond).Do()
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestRequestBuilder_RedirectPreservesBody(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/temporary", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/permanent", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/permanent", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusPermanentRedirect)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s", r.Method, body)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := map[string]struct {
+		builder *httpx.RequestBuilder
+		want    string
+	}{
+		"json":  {httpx.New(server.URL + "/temporary").Post().Json(map[string]int{"n": 1}), `POST {"n":1}`},
+		"form":  {httpx.New(server.URL + "/temporary").Put().PostForm(url.Values{"n": {"2"}}), "PUT n=2"},
+		"bytes": {httpx.New(server.URL + "/temporary").Patch().JsonRaw([]byte(`[3]`)), "PATCH [3]"},
+		"custom": {
+			httpx.New(server.URL + "/temporary").Post().
+				Body(io.NopCloser(strings.NewReader("four"))).
+				GetBody(func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("four")), nil }),
+			"POST four",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := tt.builder.Do()
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(body))
+		})
+	}
+}